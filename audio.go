@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Каталог procfs подсистемы ALSA
+const asoundDir = "/proc/asound"
+
+// Звуковая карта ALSA
+type SoundCard struct {
+	Index  string
+	ID     string
+	Name   string
+	Driver string
+	Codecs []AudioCodec
+	PCMs   []AudioPCM
+}
+
+// Кодек HDA, найденный на карте
+type AudioCodec struct {
+	Name     string
+	VendorID string
+}
+
+// PCM-устройство воспроизведения или записи
+type AudioPCM struct {
+	Device   string // Номер в формате карта-устройство, например 00-03
+	Name     string
+	Playback bool
+	Capture  bool
+}
+
+// Сведения о звуковой подсистеме и результат проверки кодеков
+type AudioInfo struct {
+	Cards         []SoundCard
+	MissingCodecs []string // Ожидаемые кодеки, которых нет в системе
+}
+
+var (
+	asoundCardRegex = regexp.MustCompile(`^\s*(\d+)\s+\[(\S+)\s*\]:\s*(.+?)\s+-\s+(.+)$`)
+	asoundPCMRegex  = regexp.MustCompile(`^(\d+)-(\d+):\s*([^:]*):`)
+)
+
+func getAudioInfo(expectedCodecs []string) (AudioInfo, error) {
+	var info AudioInfo
+
+	cards, err := os.ReadFile(filepath.Join(asoundDir, "cards"))
+	if os.IsNotExist(err) {
+		info.MissingCodecs = expectedCodecs // ALSA не загружен, ни одного кодека нет
+		return info, nil
+	}
+	if err != nil {
+		return info, err
+	}
+
+	for _, line := range strings.Split(string(cards), "\n") {
+		match := asoundCardRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		card := SoundCard{
+			Index:  match[1],
+			ID:     match[2],
+			Driver: match[3],
+			Name:   match[4],
+		}
+
+		// Имя модуля ядра точнее, чем короткое имя драйвера из /proc/asound/cards
+		driverLink := filepath.Join("/sys/class/sound", "card"+card.Index, "device", "driver")
+		if target, err := os.Readlink(driverLink); err == nil {
+			card.Driver = filepath.Base(target)
+		}
+
+		// Кодеки HDA: /proc/asound/cardN/codec#M
+		codecFiles, _ := filepath.Glob(filepath.Join(asoundDir, "card"+card.Index, "codec#*"))
+		for _, codecFile := range codecFiles {
+			data, err := os.ReadFile(codecFile)
+			if err != nil {
+				continue
+			}
+			var codec AudioCodec
+			for _, codecLine := range strings.Split(string(data), "\n") {
+				if value, ok := strings.CutPrefix(codecLine, "Codec:"); ok {
+					codec.Name = strings.TrimSpace(value)
+				} else if value, ok := strings.CutPrefix(codecLine, "Vendor Id:"); ok {
+					codec.VendorID = strings.TrimSpace(value)
+				}
+				if codec.Name != "" && codec.VendorID != "" {
+					break
+				}
+			}
+			card.Codecs = append(card.Codecs, codec)
+		}
+
+		info.Cards = append(info.Cards, card)
+	}
+
+	// PCM-устройства: "00-00: ALC256 Analog : ALC256 Analog : playback 1 : capture 1"
+	pcm, err := os.ReadFile(filepath.Join(asoundDir, "pcm"))
+	if err == nil {
+		for _, line := range strings.Split(string(pcm), "\n") {
+			match := asoundPCMRegex.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			device := AudioPCM{
+				Device:   match[1] + "-" + match[2],
+				Name:     strings.TrimSpace(match[3]),
+				Playback: strings.Contains(line, "playback"),
+				Capture:  strings.Contains(line, "capture"),
+			}
+			for i := range info.Cards {
+				if cardIndex(info.Cards[i].Index) == match[1] {
+					info.Cards[i].PCMs = append(info.Cards[i].PCMs, device)
+				}
+			}
+		}
+	}
+
+	// Проверяем наличие ожидаемых кодеков по имени или Vendor Id
+	for _, expected := range expectedCodecs {
+		if !info.hasCodec(expected) {
+			info.MissingCodecs = append(info.MissingCodecs, expected)
+		}
+	}
+
+	return info, nil
+}
+
+// Номер карты в формате /proc/asound/pcm (две цифры)
+func cardIndex(index string) string {
+	if len(index) < 2 {
+		return "0" + index
+	}
+	return index
+}
+
+// Есть ли в системе кодек, имя или Vendor Id которого содержит заданную строку
+func (a AudioInfo) hasCodec(expected string) bool {
+	expected = strings.ToLower(expected)
+	for _, card := range a.Cards {
+		for _, codec := range card.Codecs {
+			if strings.Contains(strings.ToLower(codec.Name), expected) ||
+				strings.EqualFold(codec.VendorID, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Направления PCM-устройства для отображения
+func (p AudioPCM) directions() string {
+	var directions []string
+	if p.Playback {
+		directions = append(directions, "playback")
+	}
+	if p.Capture {
+		directions = append(directions, "capture")
+	}
+	return strings.Join(directions, "/")
+}
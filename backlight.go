@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Каталог sysfs с устройствами подсветки
+const backlightDir = "/sys/class/backlight"
+
+// Устройство подсветки и уровни, через которые прошел тест
+type BacklightDevice struct {
+	Name     string
+	Type     string // firmware, platform или raw
+	Max      int    // max_brightness, доступно Max+1 уровней (от 0 до Max)
+	Original int
+	Steps    []int  // Уровни, через которые прошел тест
+	Error    string // Ошибка записи уровня, если была
+}
+
+// Число уровней, которые предлагает панель, включая 0
+func (d BacklightDevice) availableLevels() int {
+	return d.Max + 1
+}
+
+// Результат теста подсветки
+type BacklightTestResult struct {
+	Devices   []BacklightDevice
+	Confirmed bool   // Оператор подтвердил изменение яркости
+	Error     string // Устройства не найдены или не удалось их прочитать
+	Passed    bool
+}
+
+// Этап проверки подсветки
+type backlightStage struct {
+	devices    []BacklightDevice
+	step       int // Номер текущего шага, общий для всех устройств
+	steps      int
+	stepDelay  time.Duration
+	generation int
+	asking     bool // Шаги пройдены, ждем ответа оператора
+	result     BacklightTestResult
+	done       bool
+}
+
+// Чтение целого атрибута подсветки
+func readBacklightValue(device, attr string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(backlightDir, device, attr))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func writeBacklightValue(device string, level int) error {
+	return os.WriteFile(filepath.Join(backlightDir, device, "brightness"), []byte(strconv.Itoa(level)), 0644)
+}
+
+// Поиск устройств подсветки и расчет уровней от минимума до максимума
+func getBacklightDevices(steps int) ([]BacklightDevice, error) {
+	entries, err := os.ReadDir(backlightDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var devices []BacklightDevice
+	for _, entry := range entries {
+		device := BacklightDevice{Name: entry.Name()}
+		if device.Max, err = readBacklightValue(device.Name, "max_brightness"); err != nil || device.Max <= 0 {
+			continue
+		}
+		if device.Original, err = readBacklightValue(device.Name, "brightness"); err != nil {
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(backlightDir, device.Name, "type")); err == nil {
+			device.Type = strings.TrimSpace(string(data))
+		}
+
+		// Уровень 0 на многих панелях полностью гасит экран, поэтому начинаем с 1
+		for i := 0; i <= steps; i++ {
+			level := 1 + (device.Max-1)*i/steps
+			if len(device.Steps) == 0 || device.Steps[len(device.Steps)-1] != level {
+				device.Steps = append(device.Steps, level)
+			}
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// Установка уровня текущего шага на всех устройствах
+func (b *backlightStage) apply() {
+	for i := range b.devices {
+		device := &b.devices[i]
+		level := device.Steps[min(b.step, len(device.Steps)-1)]
+		if err := writeBacklightValue(device.Name, level); err != nil && device.Error == "" {
+			device.Error = err.Error()
+		}
+	}
+}
+
+// Возврат исходной яркости
+func (b *backlightStage) restore() {
+	for _, device := range b.devices {
+		writeBacklightValue(device.Name, device.Original)
+	}
+}
+
+func (b backlightStage) evaluate(confirmed bool, errText string) BacklightTestResult {
+	res := BacklightTestResult{Devices: b.devices, Confirmed: confirmed, Error: errText}
+	res.Passed = confirmed && errText == "" && len(b.devices) > 0
+	for _, device := range b.devices {
+		if device.Error != "" {
+			res.Passed = false
+		}
+	}
+	return res
+}
+
+// Очередной шаг изменения яркости
+type backlightStepMsg struct {
+	generation int
+}
+
+func backlightStepCmd(delay time.Duration, generation int) tea.Cmd {
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return backlightStepMsg{generation: generation}
+	})
+}
+
+func (backlightStage) Name() string { return "backlight" }
+
+// Запуск теста подсветки
+func (b backlightStage) Init(m model) (Stage, tea.Cmd) {
+	// Длительность шага проверена при загрузке конфигурации
+	delay, _ := time.ParseDuration(m.config.Backlight.StepDuration)
+	b = backlightStage{
+		steps:      m.config.Backlight.Steps,
+		stepDelay:  delay,
+		generation: b.generation + 1,
+	}
+
+	devices, err := getBacklightDevices(b.steps)
+	if err != nil || len(devices) == 0 {
+		errText := "no backlight devices found"
+		if err != nil {
+			errText = err.Error()
+		}
+		b.result = b.evaluate(false, errText)
+		b.done = true
+		return b, m.request(requestNext)
+	}
+
+	b.devices = devices
+	b.apply()
+	return b, backlightStepCmd(b.stepDelay, b.generation)
+}
+
+// Клавиши отвечают на вопрос теста и восстанавливают яркость при выходе
+func (b backlightStage) Update(m model, msg tea.Msg) (Stage, tea.Cmd) {
+	if b.done {
+		return b, nil
+	}
+
+	switch msg := msg.(type) {
+	case backlightStepMsg:
+		// Переход к следующему уровню, после максимума - вопрос оператору
+		if b.asking || msg.generation != b.generation {
+			return b, nil
+		}
+		b.step++
+		if b.step > b.steps {
+			b.restore()
+			b.asking = true
+			return b, nil
+		}
+		b.apply()
+		return b, backlightStepCmd(b.stepDelay, b.generation)
+
+	case stageRetryMsg:
+		return b.Init(m)
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			// Не оставляем панель на случайном уровне яркости
+			b.restore()
+			return b, tea.Quit
+		}
+
+		if !b.asking {
+			return b, nil
+		}
+
+		switch msg.String() {
+		case "y", "н":
+			b.result = b.evaluate(true, "")
+			b.done = true
+			return b, m.request(requestNext)
+		case "n", "т":
+			b.result = b.evaluate(false, "")
+			b.done = true
+			return b, m.request(requestNext)
+		case "r", "к":
+			return b, m.request(requestRetry)
+		}
+	}
+	return b, nil
+}
+
+// Белый экран во время смены яркости и вопрос оператору после
+func (b backlightStage) View(m model) string {
+	areaHeight := max(m.height-1, 0)
+
+	var status string
+	if b.asking {
+		status = "Did the panel dim and brighten smoothly? [Y] Yes [N] No [R] Repeat"
+	} else {
+		var levels []string
+		for _, device := range b.devices {
+			level := device.Steps[min(b.step, len(device.Steps)-1)]
+			levels = append(levels, fmt.Sprintf("%s %d/%d", device.Name, level, device.Max))
+		}
+		status = fmt.Sprintf("Backlight step %d/%d: %s", b.step, b.steps, strings.Join(levels, ", "))
+	}
+
+	return solidPattern("#FFFFFF")(m.width, areaHeight) + "\n" + lipgloss.NewStyle().
+		Align(lipgloss.Center).
+		Width(m.width).
+		MaxHeight(1).
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#000000")).
+		Bold(true).
+		Render(status)
+}
+
+func (b backlightStage) Result(m model) StageResult {
+	var res StageResult
+	if !b.done {
+		return res
+	}
+
+	bl := b.result
+	for _, device := range bl.Devices {
+		res.value(device.Name+" max", "%d", device.Max)
+		res.value(device.Name+" levels available", "%d", device.availableLevels())
+		res.value(device.Name+" tested steps", "%d", len(device.Steps))
+		if device.Error != "" {
+			res.Status = StageError
+			res.Reasons = append(res.Reasons, "backlight: "+device.Name+": "+device.Error)
+		}
+	}
+	if bl.Error != "" {
+		res.Status = StageError
+		res.Reasons = append(res.Reasons, "backlight: "+bl.Error)
+	}
+	if res.Status == "" && !bl.Confirmed {
+		res.fail("backlight: not confirmed by operator")
+	}
+	res.settle()
+	return res
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Каталог sysfs с источниками питания
+const powerSupplyDir = "/sys/class/power_supply"
+
+// Информация о батарее ноутбука
+type BatteryInfo struct {
+	Name         string
+	Manufacturer string
+	Model        string
+	Technology   string
+	Unit         string // mWh для energy_*, mAh для charge_*
+	DesignFull   float64
+	Full         float64
+	WearPercent  float64 // Износ относительно заводской емкости
+	CycleCount   int
+	Status       string
+	WearExceeded bool // Износ превышает допустимый порог из конфигурации
+}
+
+// Состояние питания: батареи и сетевой адаптер
+type PowerInfo struct {
+	Batteries []BatteryInfo
+	ACPresent bool // В системе есть сетевой адаптер
+	ACOnline  bool
+}
+
+// Чтение атрибута источника питания
+func readPowerSupplyAttr(dir, attr string) string {
+	data, err := os.ReadFile(filepath.Join(dir, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Чтение числового атрибута в мкВт·ч или мкА·ч
+func readPowerSupplyMicro(dir, attr string) float64 {
+	value, err := strconv.ParseFloat(readPowerSupplyAttr(dir, attr), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func getPowerInfo(maxWearPercent float64) (PowerInfo, error) {
+	var info PowerInfo
+
+	entries, err := os.ReadDir(powerSupplyDir)
+	if os.IsNotExist(err) {
+		return info, nil // Настольная система без источников питания в sysfs
+	}
+	if err != nil {
+		return info, err
+	}
+
+	for _, entry := range entries {
+		dir := filepath.Join(powerSupplyDir, entry.Name())
+
+		switch readPowerSupplyAttr(dir, "type") {
+		case "Mains":
+			info.ACPresent = true
+			if readPowerSupplyAttr(dir, "online") == "1" {
+				info.ACOnline = true
+			}
+
+		case "Battery":
+			// Батареи периферийных устройств (мыши, геймпады) пропускаем
+			if readPowerSupplyAttr(dir, "scope") == "Device" {
+				continue
+			}
+
+			battery := BatteryInfo{
+				Name:         entry.Name(),
+				Manufacturer: readPowerSupplyAttr(dir, "manufacturer"),
+				Model:        readPowerSupplyAttr(dir, "model_name"),
+				Technology:   readPowerSupplyAttr(dir, "technology"),
+				Status:       readPowerSupplyAttr(dir, "status"),
+			}
+			battery.CycleCount, _ = strconv.Atoi(readPowerSupplyAttr(dir, "cycle_count"))
+
+			// Драйверы отдают емкость либо в энергии, либо в заряде
+			if design := readPowerSupplyMicro(dir, "energy_full_design"); design > 0 {
+				battery.Unit = "mWh"
+				battery.DesignFull = design / 1000
+				battery.Full = readPowerSupplyMicro(dir, "energy_full") / 1000
+			} else if design := readPowerSupplyMicro(dir, "charge_full_design"); design > 0 {
+				battery.Unit = "mAh"
+				battery.DesignFull = design / 1000
+				battery.Full = readPowerSupplyMicro(dir, "charge_full") / 1000
+			}
+
+			if battery.DesignFull > 0 && battery.Full > 0 {
+				battery.WearPercent = (1 - battery.Full/battery.DesignFull) * 100
+				if battery.WearPercent < 0 {
+					battery.WearPercent = 0
+				}
+			}
+
+			if maxWearPercent > 0 && battery.WearPercent > maxWearPercent {
+				battery.WearExceeded = true
+			}
+
+			info.Batteries = append(info.Batteries, battery)
+		}
+	}
+
+	return info, nil
+}
+
+// Проходит ли проверка износа батарей
+func (p PowerInfo) batteryPassed() bool {
+	for _, battery := range p.Batteries {
+		if battery.WearExceeded {
+			return false
+		}
+	}
+	return true
+}
+
+// Описание состояния сетевого адаптера
+func (p PowerInfo) acState() string {
+	if !p.ACPresent {
+		return "not present"
+	}
+	if p.ACOnline {
+		return "online"
+	}
+	return "offline"
+}
+
+// Строка емкости батареи: фактическая / заводская
+func (b BatteryInfo) capacityString() string {
+	if b.DesignFull == 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%.0f / %.0f %s", b.Full, b.DesignFull, b.Unit)
+}
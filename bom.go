@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Ожидаемая комплектация модели или заказа
+type BOMSpec struct {
+	CPU              string           `json:"cpu"`               // Подстрока модели процессора
+	RAMGB            int              `json:"ram_gb"`            // Суммарный объем установленных модулей
+	RAMSlots         int              `json:"ram_slots"`         // Число установленных модулей
+	Storage          []BOMStorageSpec `json:"storage"`           // Несъемные накопители
+	GPU              string           `json:"gpu"`               // Подстрока модели видеокарты
+	NICs             []string         `json:"nics"`              // Подстроки моделей сетевых карт
+	PanelResolution  string           `json:"panel_resolution"`  // 1920x1080
+	BatteryDesignWh  float64          `json:"battery_design_wh"` // Заводская емкость, если драйвер отдает энергию
+	BatteryDesignMAh float64          `json:"battery_design_mah"`
+}
+
+// Ожидаемый накопитель
+type BOMStorageSpec struct {
+	Model  string  `json:"model"` // Подстрока модели, пусто - любая
+	SizeGB float64 `json:"size_gb"`
+}
+
+// Отклонение от ожидаемой комплектации
+type bomDeviation struct {
+	item     string
+	expected string
+	actual   string
+}
+
+func (d bomDeviation) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s", d.item, d.expected, d.actual)
+}
+
+// Ключ комплектации заказа: order:<work_order из манифеста>
+const bomOrderPrefix = "order:"
+
+// Загрузка файла комплектаций: спецификации по подстроке Product Name и по
+// заказу. Файл .yaml или .yml разбирается как YAML, остальные - как JSON.
+func loadBOMSpecs(path string) (map[string]BOMSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML приводится к JSON, чтобы поля разбирались по тем же тегам
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		doc, err := parseYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка разбора %s: %v", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("ошибка разбора %s: %v", path, err)
+		}
+	}
+
+	var specs map[string]BOMSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("ошибка разбора %s: %v", path, err)
+	}
+	return specs, nil
+}
+
+// Спецификация для устройства: комплектация заказа, если она задана, иначе по
+// самой длинной подстроке Product Name. При равной длине выбор идет по алфавиту,
+// чтобы не зависеть от порядка обхода map.
+func bomSpecFor(specs map[string]BOMSpec, order, productName string) (string, BOMSpec, bool) {
+	if order != "" {
+		if spec, ok := specs[bomOrderPrefix+order]; ok {
+			return bomOrderPrefix + order, spec, true
+		}
+	}
+
+	best, found := "", false
+	for pattern := range specs {
+		if strings.HasPrefix(pattern, bomOrderPrefix) || !containsFold(productName, pattern) {
+			continue
+		}
+		if !found || len(pattern) > len(best) || len(pattern) == len(best) && pattern < best {
+			best, found = pattern, true
+		}
+	}
+	if !found {
+		return "", BOMSpec{}, false
+	}
+	return best, specs[best], true
+}
+
+// Объем модуля памяти из dmidecode (8 GB, 8192 MB) в ГБ
+func memorySlotGB(size string) float64 {
+	fields := strings.Fields(size)
+	if len(fields) < 2 {
+		return 0
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	switch strings.ToUpper(fields[1]) {
+	case "TB":
+		return value * 1024
+	case "GB":
+		return value
+	case "MB":
+		return value / 1024
+	}
+	return 0
+}
+
+// Размер диска из lsblk (476.9G, 1T) в десятичных ГБ, как на этикетке
+var lsblkSizeRe = regexp.MustCompile(`^([\d.,]+)([KMGTP]?)`)
+
+func storageSizeGB(size string) float64 {
+	match := lsblkSizeRe.FindStringSubmatch(strings.TrimSpace(size))
+	if match == nil {
+		return 0
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", "."), 64)
+	if err != nil {
+		return 0
+	}
+	power := 0
+	if match[2] != "" {
+		power = strings.Index("KMGTP", match[2]) + 1
+	}
+	return value * math.Pow(1024, float64(power)) / 1e9
+}
+
+// Значения совпадают с допуском в процентах
+func withinTolerance(actual, expected, tolerancePercent float64) bool {
+	return math.Abs(actual-expected) <= expected*tolerancePercent/100
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// Сравнение собранной информации с ожидаемой комплектацией
+func compareBOM(info SystemInfo, spec BOMSpec, tolerancePercent float64) []bomDeviation {
+	var deviations []bomDeviation
+
+	if spec.CPU != "" && !containsFold(info.Processor.Model, spec.CPU) {
+		deviations = append(deviations, bomDeviation{"cpu", spec.CPU, info.Processor.Model})
+	}
+
+	var ramGB float64
+	for _, slot := range info.Memory.Slots {
+		ramGB += memorySlotGB(slot.Size)
+	}
+	if spec.RAMGB > 0 && ramGB != float64(spec.RAMGB) {
+		deviations = append(deviations, bomDeviation{"ram", fmt.Sprintf("%d GB", spec.RAMGB), fmt.Sprintf("%.0f GB", ramGB)})
+	}
+	if spec.RAMSlots > 0 && len(info.Memory.Slots) != spec.RAMSlots {
+		deviations = append(deviations, bomDeviation{"ram modules", strconv.Itoa(spec.RAMSlots), strconv.Itoa(len(info.Memory.Slots))})
+	}
+
+	// Каждому ожидаемому накопителю нужен свой диск; загрузочная флешка
+	// и оптический привод не считаются
+	if len(spec.Storage) > 0 {
+		var disks []StorageInfo
+		for _, disk := range info.Storage {
+			if !disk.Removable && disk.Transport != "usb" && disk.DeviceType != "rom" {
+				disks = append(disks, disk)
+			}
+		}
+		used := make([]bool, len(disks))
+		for _, want := range spec.Storage {
+			expected := fmt.Sprintf("%s %.0f GB", want.Model, want.SizeGB)
+			found := false
+			for i, disk := range disks {
+				if used[i] || !containsFold(disk.Model, want.Model) {
+					continue
+				}
+				if want.SizeGB > 0 && !withinTolerance(storageSizeGB(disk.Size), want.SizeGB, tolerancePercent) {
+					continue
+				}
+				used[i], found = true, true
+				break
+			}
+			if !found {
+				deviations = append(deviations, bomDeviation{"storage", strings.TrimSpace(expected), "no matching disk"})
+			}
+		}
+		for i, disk := range disks {
+			if !used[i] {
+				deviations = append(deviations, bomDeviation{"storage", "no disk", fmt.Sprintf("%s %s (%s)", disk.Name, disk.Model, disk.Size)})
+			}
+		}
+	}
+
+	if spec.GPU != "" && !containsFold(info.GPU.Model, spec.GPU) {
+		deviations = append(deviations, bomDeviation{"gpu", spec.GPU, info.GPU.Model})
+	}
+
+	for _, nic := range spec.NICs {
+		found := false
+		for _, net := range info.Network {
+			if containsFold(net.Model, nic) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			deviations = append(deviations, bomDeviation{"nic", nic, "not found"})
+		}
+	}
+
+	// Родной режим встроенной панели, а не текущий режим внешнего монитора
+	if spec.PanelResolution != "" && info.GPU.PanelResolution != spec.PanelResolution {
+		actual := info.GPU.PanelResolution
+		if actual == "" {
+			actual = "no internal panel"
+		}
+		deviations = append(deviations, bomDeviation{"panel", spec.PanelResolution, actual})
+	}
+
+	// Заводская емкость сравнивается в тех единицах, которые отдает драйвер
+	if spec.BatteryDesignWh > 0 || spec.BatteryDesignMAh > 0 {
+		if len(info.Power.Batteries) == 0 {
+			deviations = append(deviations, bomDeviation{"battery", "installed", "no battery"})
+		}
+		for _, battery := range info.Power.Batteries {
+			expected, unit := spec.BatteryDesignWh*1000, "mWh"
+			if battery.Unit == "mAh" {
+				expected, unit = spec.BatteryDesignMAh, "mAh"
+			}
+			if expected == 0 {
+				deviations = append(deviations, bomDeviation{"battery " + battery.Name, "design capacity in " + unit, "not in spec"})
+				continue
+			}
+			if !withinTolerance(battery.DesignFull, expected, tolerancePercent) {
+				deviations = append(deviations, bomDeviation{"battery " + battery.Name,
+					fmt.Sprintf("%.0f %s", expected, unit), fmt.Sprintf("%.0f %s", battery.DesignFull, battery.Unit)})
+			}
+		}
+	}
+
+	return deviations
+}
+
+// Проверка комплектации для текущего устройства
+func (m model) bomCheck() (string, []bomDeviation, bool) {
+	order := ""
+	if manifest := m.unitManifest(); manifest.found {
+		order = manifest.entry.Order
+	}
+	name, spec, ok := bomSpecFor(m.config.BOMSpecs, order, m.sysInfo.ProductName)
+	if !ok {
+		return "", nil, false
+	}
+	return name, compareBOM(m.sysInfo, spec, m.config.BOM.TolerancePercent), true
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBOMSpecFor(t *testing.T) {
+	specs := map[string]BOMSpec{
+		"":                 {CPU: "any"},
+		"ThinkPad":         {CPU: "generic"},
+		"ThinkPad T14":     {CPU: "t14"},
+		"ThinkPad T14 Gen": {CPU: "t14 gen"},
+		"Latitude 7440":    {CPU: "7440"},
+		"Latitude 5440":    {CPU: "5440"},
+		"order:WO-1":       {CPU: "order"},
+	}
+
+	tests := []struct {
+		name    string
+		order   string
+		product string
+		want    string
+	}{
+		{"longest pattern", "", "ThinkPad T14 Gen 2", "ThinkPad T14 Gen"},
+		{"equal length by name", "", "Latitude 7440 / Latitude 5440", "Latitude 5440"},
+		{"case insensitive", "", "thinkpad x1", "ThinkPad"},
+		{"catch-all", "", "Latitude 3540", ""},
+		{"order first", "WO-1", "ThinkPad T14 Gen 2", "order:WO-1"},
+		{"unknown order", "WO-2", "ThinkPad T14", "ThinkPad T14"},
+		{"order key is not a pattern", "", "order:WO-1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Результат не зависит от порядка обхода map
+			for range 20 {
+				name, spec, ok := bomSpecFor(specs, tt.order, tt.product)
+				if !ok || name != tt.want || !reflect.DeepEqual(spec, specs[tt.want]) {
+					t.Fatalf("bomSpecFor(%q, %q) = %q, want %q", tt.order, tt.product, name, tt.want)
+				}
+			}
+		})
+	}
+
+	if _, _, ok := bomSpecFor(map[string]BOMSpec{"Latitude": {}}, "", "ThinkPad"); ok {
+		t.Error("bomSpecFor matched an unrelated model")
+	}
+}
+
+func TestLoadBOMSpecsYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bom.yaml")
+	data := `# Комплектации
+ThinkPad T14 Gen 3:
+  cpu: "i5-1245U"
+  ram_gb: 16
+  ram_slots: 1
+  storage:
+    - model: PM9A1   # NVMe
+      size_gb: 512
+  nics: [Intel, Realtek]
+  battery_design_wh: 52.5
+order:WO-1:
+  cpu: 'i7-1265U'
+  gpu: ~
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	specs, err := loadBOMSpecs(path)
+	if err != nil {
+		t.Fatalf("loadBOMSpecs: %v", err)
+	}
+	want := map[string]BOMSpec{
+		"ThinkPad T14 Gen 3": {
+			CPU:             "i5-1245U",
+			RAMGB:           16,
+			RAMSlots:        1,
+			Storage:         []BOMStorageSpec{{Model: "PM9A1", SizeGB: 512}},
+			NICs:            []string{"Intel", "Realtek"},
+			BatteryDesignWh: 52.5,
+		},
+		"order:WO-1": {CPU: "i7-1265U"},
+	}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("loadBOMSpecs() = %+v, want %+v", specs, want)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"tab indent", "a:\n\tb: 1\n"},
+		{"duplicate key", "a: 1\na: 2\n"},
+		{"no colon", "a: 1\nb\n"},
+		{"bad indent", "a:\n    b: 1\n  c: 2\n"},
+		{"item among keys", "a: 1\n- b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseYAML([]byte(tt.data)); err == nil {
+				t.Errorf("parseYAML(%q) succeeded, want error", tt.data)
+			}
+		})
+	}
+}
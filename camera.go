@@ -0,0 +1,666 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Каталог sysfs с устройствами video4linux
+const video4linuxDir = "/sys/class/video4linux"
+
+const (
+	cameraWarmupFrames   = 5               // Первые кадры пропускаются, пока камера подбирает экспозицию
+	cameraCaptureTimeout = 5 * time.Second // Сколько ждать кадр от драйвера
+	cameraBufferCount    = 4
+)
+
+// Результат проверки камеры
+type CameraTestResult struct {
+	Device    string
+	Card      string
+	Driver    string
+	Width     int
+	Height    int
+	Format    string  // Формат кадра (fourcc), например YUYV
+	Mean      float64 // Средняя яркость кадра, 0-255
+	StdDev    float64 // Разброс яркости, у однотонного кадра близок к нулю
+	AutoCheck bool    // Кадр не черный и не однотонный
+	Confirmed bool    // Оператор подтвердил изображение
+	Error     string
+	Passed    bool
+}
+
+// Этап проверки камеры
+type cameraStage struct {
+	capturing bool
+	preview   func(width, height int) string
+	result    CameraTestResult
+	done      bool
+}
+
+// Структуры V4L2 из linux/videodev2.h, раскладка совпадает с ядром
+type v4l2Capability struct {
+	Driver       [16]byte
+	Card         [32]byte
+	BusInfo      [32]byte
+	Version      uint32
+	Capabilities uint32
+	DeviceCaps   uint32
+	Reserved     [3]uint32
+}
+
+type v4l2PixFormat struct {
+	Width        uint32
+	Height       uint32
+	PixelFormat  uint32
+	Field        uint32
+	BytesPerLine uint32
+	SizeImage    uint32
+	Colorspace   uint32
+	Priv         uint32
+	Flags        uint32
+	YcbcrEnc     uint32
+	Quantization uint32
+	XferFunc     uint32
+}
+
+type v4l2Format struct {
+	Type uint32
+	Fmt  struct {
+		_   [0]uintptr // Объединение в ядре выровнено по указателю
+		Pix v4l2PixFormat
+		_   [200 - unsafe.Sizeof(v4l2PixFormat{})]byte
+	}
+}
+
+type v4l2RequestBuffers struct {
+	Count        uint32
+	Type         uint32
+	Memory       uint32
+	Capabilities uint32
+	Flags        uint8
+	Reserved     [3]uint8
+}
+
+type v4l2Timecode struct {
+	Type     uint32
+	Flags    uint32
+	Frames   uint8
+	Seconds  uint8
+	Minutes  uint8
+	Hours    uint8
+	Userbits [4]uint8
+}
+
+type v4l2Buffer struct {
+	Index     uint32
+	Type      uint32
+	BytesUsed uint32
+	Flags     uint32
+	Field     uint32
+	Timestamp syscall.Timeval
+	Timecode  v4l2Timecode
+	Sequence  uint32
+	Memory    uint32
+	M         uintptr // Объединение offset/userptr/planes/fd, для MMAP - смещение
+	Length    uint32
+	Reserved2 uint32
+	RequestFD uint32
+}
+
+const (
+	v4l2BufTypeVideoCapture = 1
+	v4l2MemoryMmap          = 1
+	v4l2CapVideoCapture     = 0x00000001
+	v4l2CapReadWrite        = 0x01000000
+	v4l2CapStreaming        = 0x04000000
+	v4l2CapDeviceCaps       = 0x80000000
+)
+
+// Номер ioctl по правилам asm-generic/ioctl.h
+func vidioc(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | 'V'<<8 | nr
+}
+
+const (
+	iocWrite = 1
+	iocRead  = 2
+)
+
+var (
+	vidiocQueryCap  = vidioc(iocRead, 0, unsafe.Sizeof(v4l2Capability{}))
+	vidiocSetFmt    = vidioc(iocRead|iocWrite, 5, unsafe.Sizeof(v4l2Format{}))
+	vidiocReqBufs   = vidioc(iocRead|iocWrite, 8, unsafe.Sizeof(v4l2RequestBuffers{}))
+	vidiocQueryBuf  = vidioc(iocRead|iocWrite, 9, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocQBuf      = vidioc(iocRead|iocWrite, 15, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocDQBuf     = vidioc(iocRead|iocWrite, 17, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocStreamOn  = vidioc(iocWrite, 18, unsafe.Sizeof(int32(0)))
+	vidiocStreamOff = vidioc(iocWrite, 19, unsafe.Sizeof(int32(0)))
+)
+
+func ioctl(fd int, request uintptr, arg unsafe.Pointer) error {
+	for {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(arg))
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return errno
+		}
+		return nil
+	}
+}
+
+// Код формата V4L2 из четырех символов
+func fourcc(code string) uint32 {
+	return uint32(code[0]) | uint32(code[1])<<8 | uint32(code[2])<<16 | uint32(code[3])<<24
+}
+
+func fourccString(code uint32) string {
+	return string([]byte{byte(code), byte(code >> 8), byte(code >> 16), byte(code >> 24)})
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// Сведения об устройстве захвата
+type cameraDevice struct {
+	path   string
+	card   string
+	driver string
+	caps   uint32
+}
+
+func queryCamera(path string) (cameraDevice, error) {
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return cameraDevice{}, err
+	}
+	defer syscall.Close(fd)
+
+	var capability v4l2Capability
+	if err := ioctl(fd, vidiocQueryCap, unsafe.Pointer(&capability)); err != nil {
+		return cameraDevice{}, fmt.Errorf("VIDIOC_QUERYCAP: %v", err)
+	}
+
+	// У камер с узлом метаданных возможности узла отличаются от возможностей устройства
+	caps := capability.Capabilities
+	if caps&v4l2CapDeviceCaps != 0 {
+		caps = capability.DeviceCaps
+	}
+
+	return cameraDevice{
+		path:   path,
+		card:   cString(capability.Card[:]),
+		driver: cString(capability.Driver[:]),
+		caps:   caps,
+	}, nil
+}
+
+// Устройства захвата видео, найденные в sysfs
+func getCameraDevices() ([]cameraDevice, error) {
+	entries, err := filepath.Glob(filepath.Join(video4linuxDir, "video*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(entries)
+
+	var devices []cameraDevice
+	for _, entry := range entries {
+		device, err := queryCamera(filepath.Join("/dev", filepath.Base(entry)))
+		if err != nil {
+			continue
+		}
+		if device.caps&v4l2CapVideoCapture != 0 && device.caps&(v4l2CapStreaming|v4l2CapReadWrite) != 0 {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+// Захваченный кадр в сыром виде
+type cameraFrame struct {
+	width, height int
+	bytesPerLine  int
+	format        uint32
+	data          []byte
+}
+
+// Захват одного кадра после прогрева камеры
+func captureCameraFrame(device cameraDevice, width, height int) (cameraFrame, error) {
+	fd, err := syscall.Open(device.path, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return cameraFrame{}, err
+	}
+	defer syscall.Close(fd)
+
+	// Просим YUYV, драйвер может подставить ближайший поддерживаемый формат
+	format := v4l2Format{Type: v4l2BufTypeVideoCapture}
+	format.Fmt.Pix = v4l2PixFormat{Width: uint32(width), Height: uint32(height), PixelFormat: fourcc("YUYV")}
+	if err := ioctl(fd, vidiocSetFmt, unsafe.Pointer(&format)); err != nil {
+		return cameraFrame{}, fmt.Errorf("VIDIOC_S_FMT: %v", err)
+	}
+
+	pix := format.Fmt.Pix
+	frame := cameraFrame{
+		width:        int(pix.Width),
+		height:       int(pix.Height),
+		bytesPerLine: int(pix.BytesPerLine),
+		format:       pix.PixelFormat,
+	}
+
+	if device.caps&v4l2CapStreaming != 0 {
+		frame.data, err = captureMmap(fd)
+	} else {
+		frame.data, err = captureRead(fd, int(pix.SizeImage))
+	}
+	return frame, err
+}
+
+// Ожидание готовности устройства: открыто с O_NONBLOCK, поэтому EAGAIN повторяем
+func retryAgain(deadline time.Time, call func() error) error {
+	for {
+		err := call()
+		if !errors.Is(err, syscall.EAGAIN) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("камера не выдала кадр за %s", cameraCaptureTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Захват через буферы, отображенные в память (uvcvideo поддерживает только этот способ)
+func captureMmap(fd int) ([]byte, error) {
+	request := v4l2RequestBuffers{Count: cameraBufferCount, Type: v4l2BufTypeVideoCapture, Memory: v4l2MemoryMmap}
+	if err := ioctl(fd, vidiocReqBufs, unsafe.Pointer(&request)); err != nil {
+		return nil, fmt.Errorf("VIDIOC_REQBUFS: %v", err)
+	}
+	defer func() {
+		release := v4l2RequestBuffers{Type: v4l2BufTypeVideoCapture, Memory: v4l2MemoryMmap}
+		ioctl(fd, vidiocReqBufs, unsafe.Pointer(&release))
+	}()
+
+	buffers := make([][]byte, 0, request.Count)
+	defer func() {
+		for _, buffer := range buffers {
+			syscall.Munmap(buffer)
+		}
+	}()
+
+	for i := uint32(0); i < request.Count; i++ {
+		buf := v4l2Buffer{Index: i, Type: v4l2BufTypeVideoCapture, Memory: v4l2MemoryMmap}
+		if err := ioctl(fd, vidiocQueryBuf, unsafe.Pointer(&buf)); err != nil {
+			return nil, fmt.Errorf("VIDIOC_QUERYBUF: %v", err)
+		}
+		mapped, err := syscall.Mmap(fd, int64(uint32(buf.M)), int(buf.Length), syscall.PROT_READ, syscall.MAP_SHARED)
+		if err != nil {
+			return nil, fmt.Errorf("mmap: %v", err)
+		}
+		buffers = append(buffers, mapped)
+		if err := ioctl(fd, vidiocQBuf, unsafe.Pointer(&buf)); err != nil {
+			return nil, fmt.Errorf("VIDIOC_QBUF: %v", err)
+		}
+	}
+
+	bufType := int32(v4l2BufTypeVideoCapture)
+	if err := ioctl(fd, vidiocStreamOn, unsafe.Pointer(&bufType)); err != nil {
+		return nil, fmt.Errorf("VIDIOC_STREAMON: %v", err)
+	}
+	defer ioctl(fd, vidiocStreamOff, unsafe.Pointer(&bufType))
+
+	deadline := time.Now().Add(cameraCaptureTimeout)
+	for frames := 0; ; frames++ {
+		buf := v4l2Buffer{Type: v4l2BufTypeVideoCapture, Memory: v4l2MemoryMmap}
+		err := retryAgain(deadline, func() error {
+			return ioctl(fd, vidiocDQBuf, unsafe.Pointer(&buf))
+		})
+		if err != nil {
+			return nil, fmt.Errorf("VIDIOC_DQBUF: %v", err)
+		}
+
+		if frames >= cameraWarmupFrames {
+			used := min(int(buf.BytesUsed), len(buffers[buf.Index]))
+			return bytes.Clone(buffers[buf.Index][:used]), nil
+		}
+
+		if err := ioctl(fd, vidiocQBuf, unsafe.Pointer(&buf)); err != nil {
+			return nil, fmt.Errorf("VIDIOC_QBUF: %v", err)
+		}
+	}
+}
+
+// Захват вызовом read() для устройств без потокового режима
+func captureRead(fd int, size int) ([]byte, error) {
+	data := make([]byte, size)
+	deadline := time.Now().Add(cameraCaptureTimeout)
+	for frames := 0; ; frames++ {
+		var n int
+		err := retryAgain(deadline, func() error {
+			var err error
+			n, err = syscall.Read(fd, data)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read: %v", err)
+		}
+		if frames >= cameraWarmupFrames {
+			return data[:n], nil
+		}
+	}
+}
+
+// Преобразование сырого кадра в изображение
+func decodeCameraFrame(frame cameraFrame) (image.Image, error) {
+	rect := image.Rect(0, 0, frame.width, frame.height)
+	stride := frame.bytesPerLine
+
+	switch frame.format {
+	case fourcc("YUYV"):
+		if stride == 0 {
+			stride = frame.width * 2
+		}
+		if len(frame.data) < stride*frame.height {
+			return nil, fmt.Errorf("неполный кадр YUYV: %d байт", len(frame.data))
+		}
+		img := image.NewYCbCr(rect, image.YCbCrSubsampleRatio422)
+		for y := 0; y < frame.height; y++ {
+			line := frame.data[y*stride:]
+			for x := 0; x+1 < frame.width; x += 2 {
+				img.Y[y*img.YStride+x] = line[x*2]
+				img.Y[y*img.YStride+x+1] = line[x*2+2]
+				img.Cb[y*img.CStride+x/2] = line[x*2+1]
+				img.Cr[y*img.CStride+x/2] = line[x*2+3]
+			}
+		}
+		return img, nil
+
+	case fourcc("GREY"):
+		if stride == 0 {
+			stride = frame.width
+		}
+		if len(frame.data) < stride*frame.height {
+			return nil, fmt.Errorf("неполный кадр GREY: %d байт", len(frame.data))
+		}
+		return &image.Gray{Pix: frame.data, Stride: stride, Rect: rect}, nil
+
+	case fourcc("RGB3"):
+		if stride == 0 {
+			stride = frame.width * 3
+		}
+		if len(frame.data) < stride*frame.height {
+			return nil, fmt.Errorf("неполный кадр RGB3: %d байт", len(frame.data))
+		}
+		img := image.NewRGBA(rect)
+		for y := 0; y < frame.height; y++ {
+			for x := 0; x < frame.width; x++ {
+				p := frame.data[y*stride+x*3:]
+				img.SetRGBA(x, y, color.RGBA{p[0], p[1], p[2], 0xFF})
+			}
+		}
+		return img, nil
+
+	case fourcc("MJPG"), fourcc("JPEG"):
+		return jpeg.Decode(bytes.NewReader(frame.data))
+	}
+
+	return nil, fmt.Errorf("формат %s не поддерживается", fourccString(frame.format))
+}
+
+// Средняя яркость и разброс яркости кадра
+func frameLumaStats(img image.Image) (mean, stddev float64) {
+	bounds := img.Bounds()
+	var sum, sumSq, count float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			luma := float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			sum += luma
+			sumSq += luma * luma
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	mean = sum / count
+	return mean, math.Sqrt(math.Max(sumSq/count-mean*mean, 0))
+}
+
+// Превью кадра полублоками: в одной клетке терминала два пикселя по вертикали
+func cameraPreview(img image.Image, width, height int) string {
+	bounds := img.Bounds()
+	if width <= 0 || height <= 0 || bounds.Empty() {
+		return ""
+	}
+
+	// Клетка полублока примерно квадратная, сохраняем пропорции кадра
+	cols := width
+	rows := cols * bounds.Dy() / bounds.Dx() / 2
+	if rows > height {
+		rows = height
+		cols = rows * 2 * bounds.Dx() / bounds.Dy()
+	}
+	cols, rows = max(cols, 1), max(rows, 1)
+
+	hex := func(c color.Color) lipgloss.Color {
+		r, g, b, _ := c.RGBA()
+		return lipgloss.Color(fmt.Sprintf("#%02X%02X%02X", r>>8, g>>8, b>>8))
+	}
+	sample := func(col, row int) color.Color {
+		return img.At(bounds.Min.X+col*bounds.Dx()/cols, bounds.Min.Y+row*bounds.Dy()/(rows*2))
+	}
+
+	lines := make([]string, rows)
+	for row := range lines {
+		var line strings.Builder
+		for col := 0; col < cols; col++ {
+			line.WriteString(lipgloss.NewStyle().
+				Foreground(hex(sample(col, row*2))).
+				Background(hex(sample(col, row*2+1))).
+				Render("▀"))
+		}
+		lines[row] = line.String()
+	}
+
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, strings.Join(lines, "\n"))
+}
+
+// Итог захвата кадра
+type cameraCapturedMsg struct {
+	result CameraTestResult
+	image  image.Image
+}
+
+// Поиск камеры, захват и автоматическая проверка кадра
+func captureCameraCmd(cfg CameraTestConfig) tea.Cmd {
+	return func() tea.Msg {
+		var res CameraTestResult
+
+		devices, err := getCameraDevices()
+		if err != nil {
+			res.Error = err.Error()
+			return cameraCapturedMsg{result: res}
+		}
+
+		var device cameraDevice
+		for _, candidate := range devices {
+			if cfg.Device == "" || candidate.path == cfg.Device {
+				device = candidate
+				break
+			}
+		}
+		if device.path == "" {
+			res.Error = "no video capture devices found"
+			if cfg.Device != "" {
+				res.Error = "capture device " + cfg.Device + " not found"
+			}
+			return cameraCapturedMsg{result: res}
+		}
+		res.Device, res.Card, res.Driver = device.path, device.card, device.driver
+
+		frame, err := captureCameraFrame(device, cfg.Width, cfg.Height)
+		res.Width, res.Height, res.Format = frame.width, frame.height, fourccString(frame.format)
+		if err != nil {
+			res.Error = err.Error()
+			return cameraCapturedMsg{result: res}
+		}
+
+		img, err := decodeCameraFrame(frame)
+		if err != nil {
+			res.Error = err.Error()
+			return cameraCapturedMsg{result: res}
+		}
+
+		res.Mean, res.StdDev = frameLumaStats(img)
+		res.AutoCheck = res.Mean >= cfg.MinMean && res.StdDev >= cfg.MinStdDev
+		return cameraCapturedMsg{result: res, image: img}
+	}
+}
+
+func (cameraStage) Name() string { return "camera" }
+
+// Запуск теста камеры
+func (cameraStage) Init(m model) (Stage, tea.Cmd) {
+	return cameraStage{capturing: true}, captureCameraCmd(m.config.Camera)
+}
+
+func (c cameraStage) Update(m model, msg tea.Msg) (Stage, tea.Cmd) {
+	if c.done {
+		return c, nil
+	}
+
+	switch msg := msg.(type) {
+	case cameraCapturedMsg:
+		// Кадр получен или захват не удался
+		if !c.capturing {
+			return c, nil
+		}
+		c.capturing = false
+		c.result = msg.result
+		if msg.image != nil {
+			img := msg.image
+			c.preview = cachedRender(func(width, height int) string {
+				return cameraPreview(img, width, height)
+			})
+		}
+		return c, nil
+
+	case stageRetryMsg:
+		return c.Init(m)
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return c, tea.Quit
+		}
+		if c.capturing {
+			return c, nil
+		}
+
+		switch msg.String() {
+		case "y", "н":
+			// Подтверждение возможно только для кадра, прошедшего автоматическую проверку
+			if c.result.Error != "" || !c.result.AutoCheck {
+				return c, nil
+			}
+			c.result.Confirmed = true
+			c.result.Passed = true
+			c.done = true
+			return c, m.request(requestNext)
+		case "n", "т":
+			c.result.Passed = false
+			c.done = true
+			return c, m.request(requestNext)
+		case "r", "к":
+			return c, m.request(requestRetry)
+		}
+	}
+	return c, nil
+}
+
+// Превью кадра на весь экран и вопрос оператору
+func (c cameraStage) View(m model) string {
+	title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#00AAFF")).Render("Camera Test")
+
+	if c.capturing {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center,
+			fmt.Sprintf("%s\n\n%s Capturing frame...", title, m.spinner.View()))
+	}
+
+	res := c.result
+	if res.Error != "" {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, fmt.Sprintf("%s\n\n%s\n\n%s", title,
+			lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Render("Capture failed: "+res.Error),
+			"[R] Retry   [N] Mark as failed"))
+	}
+
+	check := "image OK"
+	options := "Is the image correct? [Y] Yes   [N] No   [R] Retry"
+	if !res.AutoCheck {
+		check = "BLACK OR UNIFORM FRAME"
+		options = "[R] Retry   [N] Mark as failed"
+	}
+	info := fmt.Sprintf("%s (%s) %dx%d %s | mean %.0f, stddev %.1f | %s",
+		res.Card, res.Device, res.Width, res.Height, res.Format, res.Mean, res.StdDev, check)
+
+	// Строки состояния не должны переноситься, иначе превью сдвинется
+	statusStyle := lipgloss.NewStyle().
+		Align(lipgloss.Center).
+		Width(m.width).
+		MaxHeight(1).
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#000000")).
+		Bold(true)
+
+	return c.preview(m.width, max(m.height-2, 1)) + "\n" +
+		statusStyle.Render(info) + "\n" +
+		statusStyle.Render(options)
+}
+
+func (c cameraStage) Result(m model) StageResult {
+	var res StageResult
+	if !c.done {
+		return res
+	}
+
+	cam := c.result
+	if cam.Device != "" {
+		res.value("device", "%s (%s)", cam.Device, cam.Card)
+		res.value("frame", "%dx%d %s", cam.Width, cam.Height, cam.Format)
+	}
+	if cam.Error != "" {
+		res.Status = StageError
+		res.Reasons = []string{"camera: " + cam.Error}
+		return res
+	}
+
+	res.value("mean brightness", "%.1f", cam.Mean)
+	res.value("brightness stddev", "%.1f", cam.StdDev)
+	switch {
+	case !cam.AutoCheck:
+		res.fail("camera: black or uniform frame")
+	case !cam.Confirmed:
+		res.fail("camera: rejected by operator")
+	}
+	res.settle()
+	return res
+}
@@ -0,0 +1,22 @@
+//go:build amd64 || arm64
+
+package main
+
+import "unsafe"
+
+// Раскладка структур V4L2 на 64-битных платформах: при расхождении с
+// linux/videodev2.h тест не соберется
+const (
+	_ = uint(unsafe.Sizeof(v4l2Capability{}) - 104)
+	_ = uint(104 - unsafe.Sizeof(v4l2Capability{}))
+
+	_ = uint(unsafe.Sizeof(v4l2Format{}) - 208)
+	_ = uint(208 - unsafe.Sizeof(v4l2Format{}))
+	_ = uint(unsafe.Offsetof(v4l2Format{}.Fmt) - 8)
+	_ = uint(8 - unsafe.Offsetof(v4l2Format{}.Fmt))
+
+	_ = uint(unsafe.Sizeof(v4l2Buffer{}) - 88)
+	_ = uint(88 - unsafe.Sizeof(v4l2Buffer{}))
+	_ = uint(unsafe.Offsetof(v4l2Buffer{}.M) - 64)
+	_ = uint(64 - unsafe.Offsetof(v4l2Buffer{}.M))
+)
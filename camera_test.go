@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"testing"
+)
+
+func TestDecodeCameraFrame(t *testing.T) {
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, image.NewGray(image.Rect(0, 0, 8, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		frame   cameraFrame
+		wantErr bool
+		check   func(t *testing.T, img image.Image)
+	}{
+		{
+			name:  "YUYV",
+			frame: cameraFrame{width: 2, height: 1, format: fourcc("YUYV"), data: []byte{10, 100, 20, 200}},
+			check: func(t *testing.T, img image.Image) {
+				ycc := img.(*image.YCbCr)
+				if ycc.Y[0] != 10 || ycc.Y[1] != 20 || ycc.Cb[0] != 100 || ycc.Cr[0] != 200 {
+					t.Errorf("Y=%v Cb=%v Cr=%v", ycc.Y[:2], ycc.Cb[:1], ycc.Cr[:1])
+				}
+			},
+		},
+		{
+			name: "YUYV with line padding",
+			frame: cameraFrame{width: 2, height: 2, bytesPerLine: 6, format: fourcc("YUYV"), data: []byte{
+				10, 100, 20, 200, 0, 0,
+				30, 101, 40, 201, 0, 0,
+			}},
+			check: func(t *testing.T, img image.Image) {
+				ycc := img.(*image.YCbCr)
+				if y := ycc.Y[ycc.YOffset(1, 1)]; y != 40 {
+					t.Errorf("Y(1,1) = %d, want 40", y)
+				}
+				if cr := ycc.Cr[ycc.COffset(0, 1)]; cr != 201 {
+					t.Errorf("Cr(0,1) = %d, want 201", cr)
+				}
+			},
+		},
+		{
+			name:  "GREY",
+			frame: cameraFrame{width: 2, height: 2, format: fourcc("GREY"), data: []byte{1, 2, 3, 4}},
+			check: func(t *testing.T, img image.Image) {
+				if g := img.At(1, 1).(color.Gray); g.Y != 4 {
+					t.Errorf("At(1,1) = %d, want 4", g.Y)
+				}
+			},
+		},
+		{
+			name:  "RGB3",
+			frame: cameraFrame{width: 2, height: 1, format: fourcc("RGB3"), data: []byte{1, 2, 3, 4, 5, 6}},
+			check: func(t *testing.T, img image.Image) {
+				if c := img.At(1, 0).(color.RGBA); c != (color.RGBA{4, 5, 6, 0xFF}) {
+					t.Errorf("At(1,0) = %v", c)
+				}
+			},
+		},
+		{
+			name:  "MJPG",
+			frame: cameraFrame{width: 8, height: 4, format: fourcc("MJPG"), data: jpegData.Bytes()},
+			check: func(t *testing.T, img image.Image) {
+				if b := img.Bounds(); b.Dx() != 8 || b.Dy() != 4 {
+					t.Errorf("bounds = %v", b)
+				}
+			},
+		},
+		{
+			name:    "short YUYV",
+			frame:   cameraFrame{width: 2, height: 2, format: fourcc("YUYV"), data: []byte{10, 100, 20, 200}},
+			wantErr: true,
+		},
+		{
+			name:    "short GREY",
+			frame:   cameraFrame{width: 2, height: 2, format: fourcc("GREY"), data: []byte{1, 2, 3}},
+			wantErr: true,
+		},
+		{
+			name:    "short RGB3",
+			frame:   cameraFrame{width: 1, height: 1, format: fourcc("RGB3"), data: []byte{1, 2}},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported format",
+			frame:   cameraFrame{width: 1, height: 1, format: fourcc("H264"), data: []byte{0}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := decodeCameraFrame(tt.frame)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, img)
+		})
+	}
+}
+
+func TestFrameLumaStats(t *testing.T) {
+	uniform := image.NewGray(image.Rect(0, 0, 4, 4))
+	for i := range uniform.Pix {
+		uniform.Pix[i] = 128
+	}
+
+	// Левая половина черная, правая белая
+	split := image.NewGray(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 2; x < 4; x++ {
+			split.SetGray(x, y, color.Gray{255})
+		}
+	}
+
+	tests := []struct {
+		name      string
+		img       image.Image
+		mean, std float64
+	}{
+		{"black", image.NewGray(image.Rect(0, 0, 4, 4)), 0, 0},
+		{"uniform", uniform, 128, 0},
+		{"split", split, 127.5, 127.5},
+		{"empty", image.NewGray(image.Rect(0, 0, 0, 0)), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mean, std := frameLumaStats(tt.img)
+			if math.Abs(mean-tt.mean) > 1e-9 || math.Abs(std-tt.std) > 1e-9 {
+				t.Errorf("frameLumaStats = (%v, %v), want (%v, %v)", mean, std, tt.mean, tt.std)
+			}
+		})
+	}
+}
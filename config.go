@@ -0,0 +1,419 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Путь к конфигурации станции по умолчанию
+const defaultConfigPath = "/etc/troubadour/station.json"
+
+// Конфигурация станции, загружается из JSON-файла
+type StationConfig struct {
+	Stages      []string           `json:"stages"` // Порядок этапов; если задан, флаги enabled не учитываются
+	Station     StationIdentity    `json:"station"`
+	Report      ReportConfig       `json:"report"`
+	StorageTest StorageTestConfig  `json:"storage_test"`
+	Battery     BatteryConfig      `json:"battery"`
+	Audio       AudioConfig        `json:"audio"`
+	Sensors     SensorsConfig      `json:"sensors"`
+	Keyboard    KeyboardTestConfig `json:"keyboard_test"`
+	Pointer     PointerTestConfig  `json:"pointer_test"`
+	VideoTest   VideoTestConfig    `json:"video_test"`
+	Backlight   BacklightConfig    `json:"backlight_test"`
+	Camera      CameraTestConfig   `json:"camera_test"`
+	Serial      SerialConfig       `json:"serial"`
+	BOM         BOMConfig          `json:"bom"`
+	Manifest    ManifestConfig     `json:"manifest"`
+	DefectCodes []DefectCode       `json:"defect_codes"` // Коды дефектов для заметок оператора
+
+	BOMSpecs map[string]BOMSpec `json:"-"` // Комплектации из файла bom.spec
+}
+
+// Станция и формат ID оператора
+type StationIdentity struct {
+	ID              string `json:"id"`               // Идентификатор станции, по умолчанию имя хоста
+	OperatorPattern string `json:"operator_pattern"` // Регулярное выражение для ID оператора, пусто - любой
+}
+
+// Настройки отчетов
+type ReportConfig struct {
+	// Шаблон имени файла без расширения: {station}, {operator}, {serial}, {order}, {timestamp}
+	FileName string `json:"file_name"`
+}
+
+// Сверка с ожидаемой комплектацией
+type BOMConfig struct {
+	Spec             string  `json:"spec"`              // JSON- или YAML-файл комплектаций по подстроке Product Name и по заказу
+	TolerancePercent float64 `json:"tolerance_percent"` // Допуск для объема дисков и емкости батареи
+}
+
+// Манифест заказов: серийный номер -> заказ, клиент и ожидаемая модель
+type ManifestConfig struct {
+	Path string `json:"path"` // CSV-файл с заголовком, пусто - манифест не используется
+}
+
+// Настройки проверки чтения накопителей
+type StorageTestConfig struct {
+	Enabled          bool     `json:"enabled"`
+	Mode             string   `json:"mode"`               // full - весь диск, sample - выборочные участки
+	SampleRegions    int      `json:"sample_regions"`     // Количество участков в режиме sample
+	RegionSizeMB     int      `json:"region_size_mb"`     // Размер одного участка
+	BlockSizeKB      int      `json:"block_size_kb"`      // Размер одного запроса чтения
+	MinThroughputMBs float64  `json:"min_throughput_mbs"` // Минимальная допустимая скорость, 0 - не проверять
+	Devices          []string `json:"devices"`            // Явный список устройств вместо автоопределения
+}
+
+// Настройки проверки батареи
+type BatteryConfig struct {
+	MaxWearPercent float64 `json:"max_wear_percent"` // Максимальный износ, 0 - не проверять
+}
+
+// Настройки проверки звука
+type AudioConfig struct {
+	ExpectedCodecs []string `json:"expected_codecs"` // Имя кодека (ALC256) или Vendor Id (0x10ec0256)
+}
+
+// Настройки проверки датчиков hwmon
+type SensorsConfig struct {
+	FailOnCritical bool `json:"fail_on_critical"` // Браковать устройство, если датчик достиг критического порога
+}
+
+// Настройки теста клавиатуры
+type KeyboardTestConfig struct {
+	Enabled        bool                           `json:"enabled"`
+	Layout         string                         `json:"layout"`          // ansi, iso или jis
+	RussianLegends bool                           `json:"russian_legends"` // Показывать русские надписи на клавишах
+	SkipKeys       []string                       `json:"skip_keys"`       // Клавиши, которые не требуется нажимать
+	Models         map[string]KeyboardModelConfig `json:"models"`          // Переопределения по подстроке Product Name
+}
+
+// Раскладка клавиатуры конкретной модели
+type KeyboardModelConfig struct {
+	Layout   string     `json:"layout"`
+	Rows     [][]string `json:"rows"` // Собственные ряды клавиш вместо стандартной раскладки
+	SkipKeys []string   `json:"skip_keys"`
+}
+
+// Настройки теста тачпада и мыши
+type PointerTestConfig struct {
+	Enabled         bool    `json:"enabled"`
+	MinPathCoverage float64 `json:"min_path_coverage"` // Какую долю контура нужно обвести, %; 0 - обводка не требуется
+}
+
+// Настройки теста подсветки
+type BacklightConfig struct {
+	Enabled      bool   `json:"enabled"`
+	Steps        int    `json:"steps"`         // Число шагов от минимальной яркости до максимальной
+	StepDuration string `json:"step_duration"` // Длительность одного шага (500ms, 1s)
+}
+
+// Настройки теста камеры
+type CameraTestConfig struct {
+	Enabled   bool    `json:"enabled"`
+	Device    string  `json:"device"` // Узел устройства (/dev/video0), пусто - первая найденная камера
+	Width     int     `json:"width"`  // Запрашиваемое разрешение, драйвер может выбрать ближайшее
+	Height    int     `json:"height"`
+	MinMean   float64 `json:"min_mean"`   // Минимальная средняя яркость кадра (0-255), ниже - черный кадр
+	MinStdDev float64 `json:"min_stddev"` // Минимальный разброс яркости, ниже - однотонный кадр
+}
+
+// Настройки ввода серийного номера
+type SerialConfig struct {
+	StripPrefixes     []string `json:"strip_prefixes"`      // Префиксы этикетки (S/N:, SN), снимаются перед сравнением
+	StripSuffixes     []string `json:"strip_suffixes"`      // Суффиксы этикетки
+	GS1               bool     `json:"gs1"`                 // Извлекать серийный номер (AI 21) из кодов GS1
+	ScannerAutoSubmit bool     `json:"scanner_auto_submit"` // Отправлять ввод сканера без ENTER
+	ScannerMinLength  int      `json:"scanner_min_length"`  // Минимальная длина пачки символов сканера
+	ScannerMaxGap     string   `json:"scanner_max_gap"`     // Максимальный интервал между символами сканера
+
+	Fields           []string                    `json:"fields"`             // Поля DMI для сверки: system, baseboard, chassis, uuid
+	AllowPrefix      bool                        `json:"allow_prefix"`       // Засчитывать совпадение начала номера
+	AllowSuffix      bool                        `json:"allow_suffix"`       // Засчитывать совпадение конца номера
+	MinPartialLength int                         `json:"min_partial_length"` // Минимальная длина для частичного совпадения
+	Vendors          map[string]SerialVendorRule `json:"vendors"`            // Правила по подстроке Manufacturer
+
+	PlaceholderSerials []string `json:"placeholder_serials"` // Дополнительные заглушки вместо номера в DMI
+
+	MaxAttempts int `json:"max_attempts"` // Несовпадений до блокировки, 0 - без ограничения
+	// PIN для разблокировки. Хеш короткого PIN перебирается мгновенно, поэтому
+	// PIN хранится как есть, а файл конфигурации должен быть доступен только root.
+	SupervisorPIN string `json:"supervisor_pin"`
+	// Хеш PBKDF2 пароля супервизора вместо PIN (troubadour -hash-supervisor-password).
+	// Пароль должен быть длинным: хеш короткого PIN перебирается так же быстро.
+	SupervisorPasswordHash string `json:"supervisor_password_hash"`
+}
+
+// Правило сверки серийного номера для производителя
+type SerialVendorRule struct {
+	Fields      []string `json:"fields"` // Пустой список - общие поля
+	Format      string   `json:"format"` // Регулярное выражение для номера в верхнем регистре; группа 1 - сам номер
+	AllowPrefix bool     `json:"allow_prefix"`
+	AllowSuffix bool     `json:"allow_suffix"`
+}
+
+// Интервал между символами сканера. Значение проверено при загрузке.
+func (c SerialConfig) scanMaxGap() time.Duration {
+	gap, _ := time.ParseDuration(c.ScannerMaxGap)
+	return gap
+}
+
+// Настройки видеотеста
+type VideoTestConfig struct {
+	Sequence    []VideoPatternConfig            `json:"sequence"`     // Пустая - последовательность по умолчанию
+	Models      map[string][]VideoPatternConfig `json:"models"`       // Переопределения по подстроке Product Name
+	ColorPolicy string                          `json:"color_policy"` // Терминал без true color: warn, refuse или off
+}
+
+// Один шаблон в последовательности видеотеста
+type VideoPatternConfig struct {
+	Pattern  string `json:"pattern"`  // solid, checkerboard, gradients, pixel_grid, smpte
+	Name     string `json:"name"`     // Название в интерфейсе и отчете
+	Color    string `json:"color"`    // Цвет #RRGGBB для solid
+	Duration string `json:"duration"` // Длительность (1s, 500ms) или wait - до нажатия клавиши
+}
+
+// Конфигурация со значениями по умолчанию
+func defaultStationConfig() StationConfig {
+	hostname, _ := os.Hostname()
+	return StationConfig{
+		StorageTest: StorageTestConfig{
+			Enabled:       false,
+			Mode:          "sample",
+			SampleRegions: 16,
+			RegionSizeMB:  64,
+			BlockSizeKB:   1024,
+		},
+		Keyboard: KeyboardTestConfig{
+			Layout:         "ansi",
+			RussianLegends: true,
+		},
+		Pointer: PointerTestConfig{
+			MinPathCoverage: 90,
+		},
+		VideoTest: VideoTestConfig{
+			ColorPolicy: "warn",
+		},
+		Backlight: BacklightConfig{
+			Steps:        10,
+			StepDuration: "500ms",
+		},
+		Camera: CameraTestConfig{
+			Width:     640,
+			Height:    480,
+			MinMean:   10,
+			MinStdDev: 5,
+		},
+		Serial: SerialConfig{
+			GS1:               true,
+			ScannerAutoSubmit: true,
+			ScannerMinLength:  6,
+			ScannerMaxGap:     "30ms",
+			Fields:            []string{"system"},
+			MinPartialLength:  6,
+			MaxAttempts:       3,
+		},
+		BOM: BOMConfig{
+			TolerancePercent: 5,
+		},
+		Station: StationIdentity{
+			ID: hostname,
+		},
+		Report: ReportConfig{
+			FileName: "troubadour_{station}_{operator}_{serial}_{timestamp}",
+		},
+		DefectCodes: []DefectCode{
+			{"DSP", "Display defect"},
+			{"KBD", "Keyboard defect"},
+			{"TPD", "Touchpad or mouse defect"},
+			{"CAM", "Camera defect"},
+			{"BAT", "Battery defect"},
+			{"SN", "Serial number or label problem"},
+			{"OPS", "Operator or station error"},
+			{"OTH", "Other"},
+		},
+	}
+}
+
+// Загрузка конфигурации станции. Отсутствующий файл не является ошибкой,
+// в этом случае используются значения по умолчанию.
+func loadStationConfig(path string) (StationConfig, error) {
+	cfg := defaultStationConfig()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("ошибка разбора конфигурации %s: %v", path, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return cfg, fmt.Errorf("некорректная конфигурация %s: %v", path, err)
+	}
+
+	// PIN супервизора не должен читать никто, кроме root
+	if cfg.Serial.SupervisorPIN != "" {
+		if err := checkRootOnly(path); err != nil {
+			return cfg, fmt.Errorf("serial.supervisor_pin: %v", err)
+		}
+	}
+
+	if cfg.BOM.Spec != "" {
+		if cfg.BOMSpecs, err = loadBOMSpecs(cfg.BOM.Spec); err != nil {
+			return cfg, fmt.Errorf("bom.spec: %v", err)
+		}
+	}
+
+	// Манифест перечитывается при каждом поиске, здесь только проверяется формат
+	if cfg.Manifest.Path != "" {
+		if _, _, err := readManifest(cfg.Manifest.Path); err != nil {
+			return cfg, fmt.Errorf("manifest.path: %v", err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// Файл принадлежит root и недоступен группе и остальным
+func checkRootOnly(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s доступен не только владельцу (%s), выполните chmod 600", path, info.Mode().Perm())
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Uid != 0 {
+		return fmt.Errorf("%s должен принадлежать root", path)
+	}
+	return nil
+}
+
+// Проверка значений конфигурации
+func (c StationConfig) validate() error {
+	st := c.StorageTest
+	if st.Mode != "full" && st.Mode != "sample" {
+		return fmt.Errorf("storage_test.mode должен быть full или sample, получено %q", st.Mode)
+	}
+	if st.BlockSizeKB <= 0 || st.BlockSizeKB%4 != 0 {
+		return fmt.Errorf("storage_test.block_size_kb должен быть положительным и кратным 4")
+	}
+	if st.Mode == "sample" && (st.SampleRegions <= 0 || st.RegionSizeMB <= 0) {
+		return fmt.Errorf("storage_test.sample_regions и region_size_mb должны быть положительными")
+	}
+	if c.Battery.MaxWearPercent < 0 || c.Battery.MaxWearPercent > 100 {
+		return fmt.Errorf("battery.max_wear_percent должен быть от 0 до 100")
+	}
+	if c.Pointer.MinPathCoverage < 0 || c.Pointer.MinPathCoverage > 100 {
+		return fmt.Errorf("pointer_test.min_path_coverage должен быть от 0 до 100")
+	}
+	if _, ok := keyboardLayouts[c.Keyboard.Layout]; !ok {
+		return fmt.Errorf("keyboard_test.layout: неизвестная раскладка %q", c.Keyboard.Layout)
+	}
+	if c.Backlight.Steps <= 0 {
+		return fmt.Errorf("backlight_test.steps должен быть положительным")
+	}
+	if delay, err := time.ParseDuration(c.Backlight.StepDuration); err != nil || delay <= 0 {
+		return fmt.Errorf("backlight_test.step_duration: некорректная длительность %q", c.Backlight.StepDuration)
+	}
+	if c.Camera.Width <= 0 || c.Camera.Height <= 0 {
+		return fmt.Errorf("camera_test.width и height должны быть положительными")
+	}
+	if c.Serial.SupervisorPIN != "" && c.Serial.SupervisorPasswordHash != "" {
+		return fmt.Errorf("serial: задайте только один из supervisor_pin и supervisor_password_hash")
+	}
+	if c.Serial.SupervisorPasswordHash != "" {
+		if _, err := parseSupervisorHash(c.Serial.SupervisorPasswordHash); err != nil {
+			return fmt.Errorf("serial.supervisor_password_hash: %v", err)
+		}
+	}
+	if c.Serial.ScannerMinLength <= 0 {
+		return fmt.Errorf("serial.scanner_min_length должен быть положительным")
+	}
+	if gap, err := time.ParseDuration(c.Serial.ScannerMaxGap); err != nil || gap <= 0 {
+		return fmt.Errorf("serial.scanner_max_gap: некорректная длительность %q", c.Serial.ScannerMaxGap)
+	}
+	if c.Station.ID == "" {
+		return fmt.Errorf("station.id не может быть пустым")
+	}
+	if _, err := regexp.Compile(c.Station.OperatorPattern); err != nil {
+		return fmt.Errorf("station.operator_pattern: %v", err)
+	}
+	if !strings.Contains(c.Report.FileName, "{timestamp}") {
+		return fmt.Errorf("report.file_name должен содержать {timestamp}, иначе отчеты перезапишут друг друга")
+	}
+	if strings.ContainsAny(c.Report.FileName, `/\`) {
+		return fmt.Errorf("report.file_name не может содержать путь")
+	}
+	if c.BOM.TolerancePercent < 0 {
+		return fmt.Errorf("bom.tolerance_percent не может быть отрицательным")
+	}
+	if c.Serial.MaxAttempts < 0 {
+		return fmt.Errorf("serial.max_attempts не может быть отрицательным")
+	}
+	if err := validateSerialFields("serial.fields", c.Serial.Fields); err != nil {
+		return err
+	}
+	for name, vendor := range c.Serial.Vendors {
+		if err := validateSerialFields(fmt.Sprintf("serial.vendors[%s].fields", name), vendor.Fields); err != nil {
+			return err
+		}
+		if _, err := regexp.Compile(vendor.Format); err != nil {
+			return fmt.Errorf("serial.vendors[%s].format: %v", name, err)
+		}
+	}
+	codes := make(map[string]bool)
+	for _, defect := range c.DefectCodes {
+		if defect.Code == "" {
+			return fmt.Errorf("defect_codes: пустой код")
+		}
+		if codes[defect.Code] {
+			return fmt.Errorf("defect_codes: код %q указан дважды", defect.Code)
+		}
+		codes[defect.Code] = true
+	}
+	if _, err := buildPipeline(c); err != nil {
+		return fmt.Errorf("stages: %v", err)
+	}
+	switch c.VideoTest.ColorPolicy {
+	case "warn", "refuse", "off":
+	default:
+		return fmt.Errorf("video_test.color_policy должен быть warn, refuse или off, получено %q", c.VideoTest.ColorPolicy)
+	}
+	if _, err := buildVideoSequence(c.VideoTest.Sequence); err != nil {
+		return fmt.Errorf("video_test.sequence: %v", err)
+	}
+	for name, sequence := range c.VideoTest.Models {
+		if _, err := buildVideoSequence(sequence); err != nil {
+			return fmt.Errorf("video_test.models[%s]: %v", name, err)
+		}
+	}
+	for name, override := range c.Keyboard.Models {
+		if _, ok := keyboardLayouts[override.Layout]; override.Layout != "" && !ok {
+			return fmt.Errorf("keyboard_test.models[%s].layout: неизвестная раскладка %q", name, override.Layout)
+		}
+	}
+	return nil
+}
+
+// Проверка списка полей DMI для сверки серийного номера
+func validateSerialFields(key string, fields []string) error {
+	for _, field := range fields {
+		if !slices.Contains(serialFields, field) {
+			return fmt.Errorf("%s: неизвестное поле %q, допустимы %v", key, field, serialFields)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Каталог sysfs с датчиками hwmon
+const hwmonDir = "/sys/class/hwmon"
+
+// Период обновления показаний датчиков на экране информации
+const sensorsRefreshInterval = 2 * time.Second
+
+// Показание одного датчика
+type SensorReading struct {
+	Kind     string // temp, fan, in
+	Label    string
+	Value    float64
+	Unit     string
+	Max      float64 // 0 - порог не задан
+	Crit     float64 // 0 - порог не задан
+	Critical bool    // Значение достигло критического порога
+}
+
+// Микросхема мониторинга и ее датчики
+type SensorChip struct {
+	Name    string
+	Sensors []SensorReading
+}
+
+// Снимок всех датчиков на момент чтения
+type SensorsInfo struct {
+	Chips   []SensorChip
+	ReadAt  time.Time
+	Warning string // Ошибка чтения hwmon, если была
+}
+
+// Каналы hwmon и множители для приведения к единицам измерения
+var hwmonChannels = []struct {
+	kind  string
+	unit  string
+	scale float64
+}{
+	{"temp", "°C", 1000},
+	{"fan", "RPM", 1},
+	{"in", "V", 1000},
+}
+
+var hwmonInputRegex = regexp.MustCompile(`^(temp|fan|in)(\d+)_input$`)
+
+// Чтение числового атрибута hwmon
+func readHwmonValue(path string, scale float64) (float64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value / scale, true
+}
+
+func getSensorsInfo() SensorsInfo {
+	info := SensorsInfo{ReadAt: time.Now()}
+
+	chipDirs, err := os.ReadDir(hwmonDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			info.Warning = err.Error()
+		}
+		return info
+	}
+
+	for _, chipDir := range chipDirs {
+		dir := filepath.Join(hwmonDir, chipDir.Name())
+		chip := SensorChip{Name: chipDir.Name()}
+		if name, err := os.ReadFile(filepath.Join(dir, "name")); err == nil {
+			chip.Name = strings.TrimSpace(string(name))
+		}
+
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range files {
+			match := hwmonInputRegex.FindStringSubmatch(file.Name())
+			if match == nil {
+				continue
+			}
+			kind, index := match[1], match[2]
+			prefix := filepath.Join(dir, kind+index)
+			channel := hwmonChannels[sensorKindOrder(kind)]
+
+			value, ok := readHwmonValue(prefix+"_input", channel.scale)
+			if !ok {
+				continue
+			}
+
+			reading := SensorReading{
+				Kind:  kind,
+				Label: kind + index,
+				Value: value,
+				Unit:  channel.unit,
+			}
+			if label, err := os.ReadFile(prefix + "_label"); err == nil {
+				reading.Label = strings.TrimSpace(string(label))
+			}
+			reading.Max, _ = readHwmonValue(prefix+"_max", channel.scale)
+			reading.Crit, _ = readHwmonValue(prefix+"_crit", channel.scale)
+			reading.Critical = reading.Crit > 0 && reading.Value >= reading.Crit
+
+			chip.Sensors = append(chip.Sensors, reading)
+		}
+
+		if len(chip.Sensors) == 0 {
+			continue
+		}
+
+		// Стабильный порядок: температуры, вентиляторы, напряжения
+		sort.SliceStable(chip.Sensors, func(i, j int) bool {
+			if chip.Sensors[i].Kind != chip.Sensors[j].Kind {
+				return sensorKindOrder(chip.Sensors[i].Kind) < sensorKindOrder(chip.Sensors[j].Kind)
+			}
+			return chip.Sensors[i].Label < chip.Sensors[j].Label
+		})
+		info.Chips = append(info.Chips, chip)
+	}
+
+	return info
+}
+
+func sensorKindOrder(kind string) int {
+	for i, channel := range hwmonChannels {
+		if channel.kind == kind {
+			return i
+		}
+	}
+	return len(hwmonChannels)
+}
+
+// Датчики, превысившие критический порог
+func (s SensorsInfo) criticalSensors() []string {
+	var critical []string
+	for _, chip := range s.Chips {
+		for _, sensor := range chip.Sensors {
+			if sensor.Critical {
+				critical = append(critical, fmt.Sprintf("%s/%s", chip.Name, sensor.Label))
+			}
+		}
+	}
+	return critical
+}
+
+// Форматирование показания с порогами
+func (r SensorReading) String() string {
+	format := "%.1f"
+	if r.Kind == "fan" {
+		format = "%.0f"
+	} else if r.Kind == "in" {
+		format = "%.3f"
+	}
+
+	text := fmt.Sprintf("%s: "+format+" %s", r.Label, r.Value, r.Unit)
+	var limits []string
+	if r.Max > 0 {
+		limits = append(limits, fmt.Sprintf("max "+format, r.Max))
+	}
+	if r.Crit > 0 {
+		limits = append(limits, fmt.Sprintf("crit "+format, r.Crit))
+	}
+	if len(limits) > 0 {
+		text += " (" + strings.Join(limits, ", ") + ")"
+	}
+	return text
+}
+
+// Обновление показаний датчиков
+type sensorsUpdateMsg struct {
+	sensors SensorsInfo
+}
+
+// Периодическое чтение датчиков
+func sensorsTickCmd() tea.Cmd {
+	return tea.Tick(sensorsRefreshInterval, func(time.Time) tea.Msg {
+		return sensorsUpdateMsg{sensors: getSensorsInfo()}
+	})
+}
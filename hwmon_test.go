@@ -0,0 +1,37 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCriticalSensorsResult(t *testing.T) {
+	sensors := SensorsInfo{Chips: []SensorChip{{
+		Name: "coretemp",
+		Sensors: []SensorReading{
+			{Kind: "temp", Label: "Package id 0", Value: 101, Unit: "°C", Crit: 100, Critical: true},
+			{Kind: "temp", Label: "Core 0", Value: 60, Unit: "°C", Crit: 100},
+		},
+	}}}
+
+	for _, failOnCritical := range []bool{false, true} {
+		m := model{
+			config:  StationConfig{Sensors: SensorsConfig{FailOnCritical: failOnCritical}},
+			sysInfo: SystemInfo{Sensors: sensors},
+		}
+		res := infoStage{}.Result(m)
+
+		failed := slices.Contains(res.Reasons, "sensors over critical: coretemp/Package id 0")
+		if failed != failOnCritical {
+			t.Errorf("fail_on_critical=%v: reasons %q", failOnCritical, res.Reasons)
+		}
+		if failOnCritical && res.Status != StageFail {
+			t.Errorf("fail_on_critical=%v: status %q, want %q", failOnCritical, res.Status, StageFail)
+		}
+		// Без настройки превышение остается в отчете как значение
+		marked := slices.Contains(res.Values, StageValue{Name: "sensors over critical", Value: "coretemp/Package id 0"})
+		if marked == failOnCritical {
+			t.Errorf("fail_on_critical=%v: values %q", failOnCritical, res.Values)
+		}
+	}
+}
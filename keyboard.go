@@ -0,0 +1,489 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Описание клавиши на экранной раскладке
+type keyDef struct {
+	id       string
+	label    string   // Латинская надпись
+	ru       string   // Русская надпись
+	width    float64  // Ширина в условных единицах (1 - обычная клавиша)
+	match    []string // Строки tea.KeyMsg, которые засчитывают клавишу
+	optional bool     // Терминал не передает нажатие (Fn, Win, Caps Lock) или не отличает его от другой клавиши
+}
+
+// Раскладка ЙЦУКЕН: русская буква по положению клавиши QWERTY.
+// Используется и для отметки клавиш, и для исправления ввода серийного номера.
+var russianKeyMap = map[rune]rune{
+	'ё': '`', 'й': 'q', 'ц': 'w', 'у': 'e', 'к': 'r', 'е': 't', 'н': 'y', 'г': 'u',
+	'ш': 'i', 'щ': 'o', 'з': 'p', 'х': '[', 'ъ': ']', 'ф': 'a', 'ы': 's', 'в': 'd',
+	'а': 'f', 'п': 'g', 'р': 'h', 'о': 'j', 'л': 'k', 'д': 'l', 'ж': ';', 'э': '\'',
+	'я': 'z', 'ч': 'x', 'с': 'c', 'м': 'v', 'и': 'b', 'т': 'n', 'ь': 'm', 'б': ',',
+	'ю': '.',
+}
+
+// Каталог клавиш ноутбука. Символы с Shift перечислены в match, чтобы
+// нажатие засчитывалось в любом регистре.
+var keyboardKeys = func() map[string]keyDef {
+	keys := []keyDef{
+		{id: "esc", label: "Esc", width: 1, match: []string{"esc"}},
+		{id: "delete", label: "Del", width: 1, match: []string{"delete"}},
+		{id: "insert", label: "Ins", width: 1, match: []string{"insert"}},
+		{id: "home", label: "Home", width: 1, match: []string{"home"}},
+		{id: "end", label: "End", width: 1, match: []string{"end"}},
+		{id: "pgup", label: "PgUp", width: 1, match: []string{"pgup"}},
+		{id: "pgdown", label: "PgDn", width: 1, match: []string{"pgdown"}},
+		{id: "backspace", label: "Bksp", width: 2, match: []string{"backspace"}},
+		{id: "tab", label: "Tab", width: 1.5, match: []string{"tab"}},
+		{id: "enter", label: "Enter", width: 2.25, match: []string{"enter"}},
+		{id: "caps", label: "Caps", width: 1.75, optional: true},
+		{id: "shift", label: "Shift L/R", width: 2.25},
+		{id: "ctrl", label: "Ctrl L/R", width: 1.25},
+		{id: "alt", label: "Alt L/R", width: 1.25},
+		{id: "lshift", label: "Shift", width: 2.25},
+		{id: "rshift", label: "Shift", width: 2.75},
+		{id: "lctrl", label: "Ctrl", width: 1.25},
+		{id: "rctrl", label: "Ctrl", width: 1.25},
+		{id: "lalt", label: "Alt", width: 1.25},
+		{id: "ralt", label: "Alt", width: 1.25},
+		{id: "fn", label: "Fn", width: 1, optional: true},
+		{id: "win", label: "Win", width: 1.25, optional: true},
+		{id: "menu", label: "Menu", width: 1, optional: true},
+		{id: "space", label: "Space", width: 5.5, match: []string{" "}},
+		{id: "left", label: "←", width: 1, match: []string{"left"}},
+		{id: "up", label: "↑", width: 1, match: []string{"up"}},
+		{id: "down", label: "↓", width: 1, match: []string{"down"}},
+		{id: "right", label: "→", width: 1, match: []string{"right"}},
+		{id: "`", label: "`", ru: "Ё", width: 1, match: []string{"`", "~"}},
+		{id: "1", label: "1", width: 1, match: []string{"1", "!"}},
+		{id: "2", label: "2", width: 1, match: []string{"2", "@", "\""}},
+		{id: "3", label: "3", width: 1, match: []string{"3", "#", "№"}},
+		{id: "4", label: "4", width: 1, match: []string{"4", "$"}},
+		{id: "5", label: "5", width: 1, match: []string{"5", "%"}},
+		{id: "6", label: "6", width: 1, match: []string{"6", "^"}},
+		{id: "7", label: "7", width: 1, match: []string{"7", "&"}},
+		{id: "8", label: "8", width: 1, match: []string{"8", "*"}},
+		{id: "9", label: "9", width: 1, match: []string{"9", "("}},
+		{id: "0", label: "0", width: 1, match: []string{"0", ")"}},
+		{id: "-", label: "-", width: 1, match: []string{"-", "_"}},
+		{id: "=", label: "=", width: 1, match: []string{"=", "+"}},
+		{id: "[", label: "[", ru: "Х", width: 1, match: []string{"[", "{"}},
+		{id: "]", label: "]", ru: "Ъ", width: 1, match: []string{"]", "}"}},
+		{id: "\\", label: "\\", width: 1.5, match: []string{"\\", "|"}},
+		{id: ";", label: ";", ru: "Ж", width: 1, match: []string{";", ":"}},
+		{id: "'", label: "'", ru: "Э", width: 1, match: []string{"'", "\""}},
+		{id: ",", label: ",", ru: "Б", width: 1, match: []string{",", "<"}},
+		{id: ".", label: ".", ru: "Ю", width: 1, match: []string{".", ">"}},
+		{id: "/", label: "/", ru: ".", width: 1, match: []string{"/", "?"}},
+		// Дополнительные клавиши ISO. Клавиша # на месте ANSI \ дает те же
+		// символы, клавиша < - те же, что Shift+, и Shift+., поэтому она не
+		// проверяется.
+		{id: "iso_hash", label: "#", width: 1, match: []string{"\\", "|", "#"}},
+		{id: "iso_lt", label: "<", width: 1, match: []string{"<", ">"}},
+		// Клавиши JIS. ¥ и ろ дают \, как и клавиша \, поэтому ни одна из трех
+		// не проверяется.
+		{id: "jis_yen", label: "¥", width: 1, match: []string{"\\", "|"}},
+		{id: "jis_ro", label: "ろ", width: 1, match: []string{"\\", "_"}},
+		{id: "muhenkan", label: "無変換", width: 1.25, optional: true},
+		{id: "henkan", label: "変換", width: 1.25, optional: true},
+		{id: "kana", label: "かな", width: 1.25, optional: true},
+	}
+
+	for i := 1; i <= 12; i++ {
+		name := fmt.Sprintf("f%d", i)
+		keys = append(keys, keyDef{id: name, label: strings.ToUpper(name), width: 1, match: []string{name}})
+	}
+
+	// Буквы: латиница, русская надпись и русская буква как вариант ввода
+	for ru, latin := range russianKeyMap {
+		if !unicode.IsLetter(latin) {
+			continue
+		}
+		keys = append(keys, keyDef{
+			id:    string(latin),
+			label: strings.ToUpper(string(latin)),
+			ru:    strings.ToUpper(string(ru)),
+			width: 1,
+			match: []string{string(latin)},
+		})
+	}
+
+	catalog := make(map[string]keyDef, len(keys))
+	for _, key := range keys {
+		catalog[key.id] = key
+	}
+	return catalog
+}()
+
+// Левый и правый модификатор. Терминал не сообщает сторону нажатия, поэтому
+// пара проверяется одной общей клавишей, а правая рисуется как непроверяемая.
+var modifierSides = map[string]string{
+	"lshift": "shift", "rshift": "shift",
+	"lctrl": "ctrl", "rctrl": "ctrl",
+	"lalt": "alt", "ralt": "alt",
+}
+
+// Ряды раскладок ноутбучной клавиатуры (без цифрового блока)
+var keyboardLayouts = map[string][][]string{
+	"ansi": {
+		{"esc", "f1", "f2", "f3", "f4", "f5", "f6", "f7", "f8", "f9", "f10", "f11", "f12", "delete"},
+		{"`", "1", "2", "3", "4", "5", "6", "7", "8", "9", "0", "-", "=", "backspace"},
+		{"tab", "q", "w", "e", "r", "t", "y", "u", "i", "o", "p", "[", "]", "\\"},
+		{"caps", "a", "s", "d", "f", "g", "h", "j", "k", "l", ";", "'", "enter"},
+		{"lshift", "z", "x", "c", "v", "b", "n", "m", ",", ".", "/", "rshift"},
+		{"lctrl", "fn", "win", "lalt", "space", "ralt", "rctrl", "left", "up", "down", "right"},
+	},
+	"iso": {
+		{"esc", "f1", "f2", "f3", "f4", "f5", "f6", "f7", "f8", "f9", "f10", "f11", "f12", "delete"},
+		{"`", "1", "2", "3", "4", "5", "6", "7", "8", "9", "0", "-", "=", "backspace"},
+		{"tab", "q", "w", "e", "r", "t", "y", "u", "i", "o", "p", "[", "]", "enter"},
+		{"caps", "a", "s", "d", "f", "g", "h", "j", "k", "l", ";", "'", "iso_hash"},
+		{"lshift", "iso_lt", "z", "x", "c", "v", "b", "n", "m", ",", ".", "/", "rshift"},
+		{"lctrl", "fn", "win", "lalt", "space", "ralt", "rctrl", "left", "up", "down", "right"},
+	},
+	"jis": {
+		{"esc", "f1", "f2", "f3", "f4", "f5", "f6", "f7", "f8", "f9", "f10", "f11", "f12", "delete"},
+		{"`", "1", "2", "3", "4", "5", "6", "7", "8", "9", "0", "-", "=", "jis_yen", "backspace"},
+		{"tab", "q", "w", "e", "r", "t", "y", "u", "i", "o", "p", "[", "]", "enter"},
+		{"caps", "a", "s", "d", "f", "g", "h", "j", "k", "l", ";", "'", "\\"},
+		{"lshift", "z", "x", "c", "v", "b", "n", "m", ",", ".", "/", "jis_ro", "rshift"},
+		{"lctrl", "fn", "win", "lalt", "muhenkan", "space", "henkan", "kana", "ralt", "rctrl", "left", "up", "down", "right"},
+	},
+}
+
+// Результат теста клавиатуры
+type KeyboardTestResult struct {
+	Layout       string
+	Total        int      // Количество обязательных клавиш
+	Seen         int      // Сколько из них нажато
+	Missing      []string // Обязательные клавиши, которые так и не были нажаты
+	Unsided      []string // Модификаторы, у которых левая и правая клавиши не различались
+	Unverifiable []string // Клавиши, которые терминал не отличает от других
+	Failed       bool     // Оператор отметил тест как проваленный
+	Passed       bool
+}
+
+// Этап проверки клавиатуры
+type keyboardStage struct {
+	layout       string
+	russian      bool
+	rows         [][]keyDef
+	seen         map[string]bool
+	matchIndex   map[string]string // Строка нажатия -> клавиша раскладки
+	unsided      []string          // Общие клавиши вместо пар левый/правый
+	unverifiable []string          // Клавиши без собственного символа на раскладке
+	lastEsc      bool              // Предыдущее нажатие было Esc
+	confirm      bool              // Ожидание подтверждения провала
+	result       KeyboardTestResult
+	done         bool
+}
+
+// Выбор раскладки для модели: переопределение по Product Name или общая
+func keyboardLayoutFor(cfg KeyboardTestConfig, productName string) (string, [][]string, []string) {
+	layout, rows, skip := cfg.Layout, keyboardLayouts[cfg.Layout], cfg.SkipKeys
+
+	for pattern, override := range cfg.Models {
+		if !strings.Contains(strings.ToLower(productName), strings.ToLower(pattern)) {
+			continue
+		}
+		if override.Layout != "" {
+			layout, rows = override.Layout, keyboardLayouts[override.Layout]
+		}
+		if len(override.Rows) > 0 {
+			layout, rows = pattern, override.Rows
+		}
+		skip = append(append([]string(nil), skip...), override.SkipKeys...)
+		break
+	}
+
+	return layout, rows, skip
+}
+
+func newKeyboardTest(cfg KeyboardTestConfig, productName string) keyboardStage {
+	layout, rowIDs, skip := keyboardLayoutFor(cfg, productName)
+
+	state := keyboardStage{
+		layout:     layout,
+		russian:    cfg.RussianLegends,
+		seen:       make(map[string]bool),
+		matchIndex: make(map[string]string),
+	}
+
+	skipped := make(map[string]bool)
+	for _, id := range skip {
+		skipped[id] = true
+	}
+
+	for _, ids := range rowIDs {
+		var row []keyDef
+		for _, id := range ids {
+			key, ok := keyboardKeys[id]
+			if !ok {
+				// Неизвестная клавиша из конфигурации рисуется, но не проверяется
+				key = keyDef{id: id, label: id, width: 1, optional: true}
+			}
+			if combined, ok := modifierSides[id]; ok {
+				if slices.Contains(state.unsided, combined) {
+					key.optional = true
+				} else {
+					width := key.width
+					key = keyboardKeys[combined]
+					key.width = width
+					state.unsided = append(state.unsided, combined)
+				}
+			}
+			if skipped[id] {
+				key.optional = true
+			}
+			row = append(row, key)
+		}
+		state.rows = append(state.rows, row)
+	}
+
+	// Символ, который дают несколько клавиш раскладки, не засчитывает ни одну:
+	// терминал не сообщает, какая из них нажата. Клавиша без собственного
+	// символа рисуется как непроверяемая, как правые модификаторы.
+	owners := make(map[string][]string)
+	for _, row := range state.rows {
+		for _, key := range row {
+			for _, match := range key.match {
+				if !slices.Contains(owners[match], key.id) {
+					owners[match] = append(owners[match], key.id)
+				}
+			}
+		}
+	}
+	for r, row := range state.rows {
+		for c, key := range row {
+			distinct := false
+			for _, match := range key.match {
+				if len(owners[match]) == 1 {
+					state.matchIndex[match] = key.id
+					distinct = true
+				}
+			}
+			if len(key.match) > 0 && !distinct && !key.optional {
+				state.rows[r][c].optional = true
+				state.unverifiable = append(state.unverifiable, key.id)
+			}
+		}
+	}
+
+	return state
+}
+
+// Отметка клавиш по нажатию. Модификаторы терминал передает только
+// в сочетании с другой клавишей и без стороны, поэтому по префиксу
+// засчитывается общая клавиша модификатора.
+func (k *keyboardStage) press(msg tea.KeyMsg) {
+	name := msg.String()
+
+	if msg.Alt {
+		k.seen["alt"] = true
+		name = strings.TrimPrefix(name, "alt+")
+	}
+	if strings.HasPrefix(name, "ctrl+") {
+		k.seen["ctrl"] = true
+		name = strings.TrimPrefix(name, "ctrl+")
+	}
+	if strings.HasPrefix(name, "shift+") {
+		k.seen["shift"] = true
+		name = strings.TrimPrefix(name, "shift+")
+	}
+
+	if msg.Type != tea.KeyRunes {
+		k.markMatch(name)
+		return
+	}
+
+	for _, r := range msg.Runes {
+		if unicode.IsUpper(r) {
+			k.seen["shift"] = true
+			r = unicode.ToLower(r)
+		}
+		// При активной русской раскладке приходят кириллические символы
+		if latin, ok := russianKeyMap[r]; ok {
+			r = latin
+		}
+		k.markMatch(string(r))
+	}
+}
+
+func (k *keyboardStage) markMatch(name string) {
+	if id, ok := k.matchIndex[name]; ok {
+		k.seen[id] = true
+	}
+}
+
+// Итог теста по текущему состоянию
+func (k keyboardStage) evaluate(failed bool) KeyboardTestResult {
+	res := KeyboardTestResult{Layout: k.layout, Failed: failed, Unsided: k.unsided, Unverifiable: k.unverifiable}
+	for _, row := range k.rows {
+		for _, key := range row {
+			if key.optional {
+				continue
+			}
+			res.Total++
+			if k.seen[key.id] {
+				res.Seen++
+			} else {
+				res.Missing = append(res.Missing, key.id)
+			}
+		}
+	}
+	sort.Strings(res.Missing)
+	res.Passed = !failed && len(res.Missing) == 0
+	return res
+}
+
+// Все обязательные клавиши нажаты
+func (k keyboardStage) complete() bool {
+	return len(k.evaluate(false).Missing) == 0
+}
+
+func (keyboardStage) Name() string { return "keyboard" }
+
+func (keyboardStage) Init(m model) (Stage, tea.Cmd) {
+	return newKeyboardTest(m.config.Keyboard, m.sysInfo.ProductName), nil
+}
+
+// Все нажатия, включая q и ctrl+c, относятся к тесту. Двойной Esc открывает
+// запрос на провал теста: все остальные клавиши нужны для самой проверки.
+func (k keyboardStage) Update(m model, msg tea.Msg) (Stage, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok || k.done {
+		return k, nil
+	}
+
+	if k.confirm {
+		switch key.String() {
+		case "f", "F", "а", "А":
+			k.result = k.evaluate(true)
+			k.done = true
+			return k, m.request(requestNext)
+		}
+		k.confirm = false
+		return k, nil
+	}
+
+	k.press(key)
+
+	if key.Type == tea.KeyEsc {
+		if k.lastEsc {
+			k.confirm = true
+			k.lastEsc = false
+			return k, nil
+		}
+		k.lastEsc = true
+	} else {
+		k.lastEsc = false
+	}
+
+	if k.complete() {
+		k.result = k.evaluate(false)
+		k.done = true
+		return k, m.request(requestNext)
+	}
+	return k, nil
+}
+
+// Отрисовка клавиатуры на весь экран
+func (k keyboardStage) View(m model) string {
+	// Ширина условной единицы по самому широкому ряду
+	maxUnits := 1.0
+	for _, row := range k.rows {
+		units := 0.0
+		for _, key := range row {
+			units += key.width
+		}
+		if units > maxUnits {
+			maxUnits = units
+		}
+	}
+	unit := int(float64(m.width) / maxUnits)
+	if unit < 4 {
+		unit = 4
+	}
+
+	seenStyle := lipgloss.NewStyle().Background(lipgloss.Color("#00AA00")).Foreground(lipgloss.Color("#FFFFFF")).Bold(true)
+	waitStyle := lipgloss.NewStyle().Background(lipgloss.Color("#3C3C3C")).Foreground(lipgloss.Color("#E8E8E8"))
+	optionalStyle := lipgloss.NewStyle().Background(lipgloss.Color("#1D1D1D")).Foreground(lipgloss.Color("#666666"))
+
+	var rows []string
+	for _, row := range k.rows {
+		var top, bottom []string
+		for _, key := range row {
+			style := waitStyle
+			if k.seen[key.id] {
+				style = seenStyle
+			} else if key.optional {
+				style = optionalStyle
+			}
+
+			width := int(key.width*float64(unit)) - 1
+			ru := ""
+			if k.russian {
+				ru = key.ru
+			}
+			top = append(top, style.Width(width).Align(lipgloss.Center).Render(key.label), " ")
+			bottom = append(bottom, style.Width(width).Align(lipgloss.Center).Render(ru), " ")
+		}
+		rows = append(rows,
+			lipgloss.JoinHorizontal(lipgloss.Top, top...),
+			lipgloss.JoinHorizontal(lipgloss.Top, bottom...),
+			"",
+		)
+	}
+
+	res := k.evaluate(false)
+	status := fmt.Sprintf("Keyboard test [%s]: %d/%d keys   |   Esc Esc - mark as failed", k.layout, res.Seen, res.Total)
+	if k.confirm {
+		status = "Mark keyboard test as FAILED? [F] Yes   [any other key] Continue testing"
+	}
+
+	keyboard := lipgloss.Place(m.width, m.height-1, lipgloss.Center, lipgloss.Center,
+		lipgloss.JoinVertical(lipgloss.Left, rows...))
+
+	return keyboard + "\n" + lipgloss.NewStyle().
+		Align(lipgloss.Center).
+		Width(m.width).
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#000000")).
+		Bold(true).
+		Render(status)
+}
+
+func (k keyboardStage) Result(m model) StageResult {
+	var res StageResult
+	if !k.done {
+		return res
+	}
+
+	kb := k.result
+	res.value("layout", "%s", kb.Layout)
+	res.value("keys pressed", "%d of %d", kb.Seen, kb.Total)
+	if len(kb.Unsided) > 0 {
+		res.value("left/right not tested separately", "%s", strings.Join(kb.Unsided, " "))
+	}
+	if len(kb.Unverifiable) > 0 {
+		res.value("not distinguishable by terminal", "%s", strings.Join(kb.Unverifiable, " "))
+	}
+	if len(kb.Missing) > 0 {
+		res.value("never pressed", "%s", strings.Join(kb.Missing, " "))
+		res.fail("keyboard: %d keys never pressed", len(kb.Missing))
+	}
+	if kb.Failed {
+		res.fail("keyboard: marked failed by operator")
+	}
+	res.settle()
+	return res
+}
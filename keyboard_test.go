@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestKeyboardLayoutsReachable(t *testing.T) {
+	for layout := range keyboardLayouts {
+		t.Run(layout, func(t *testing.T) {
+			k := newKeyboardTest(KeyboardTestConfig{Layout: layout}, "")
+
+			// Каждую обязательную клавишу можно засчитать своим символом
+			for _, row := range k.rows {
+				for _, key := range row {
+					if key.optional || len(key.match) == 0 {
+						continue
+					}
+					reachable := false
+					for _, match := range key.match {
+						if k.matchIndex[match] == key.id {
+							reachable = true
+						}
+					}
+					if !reachable {
+						t.Errorf("key %q is required but no input marks it", key.id)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestKeyboardAmbiguousKeys(t *testing.T) {
+	tests := []struct {
+		layout       string
+		unverifiable []string
+		press        string
+		want         string // Засчитанная клавиша, пусто - никакая
+	}{
+		{"ansi", nil, "<", ","},
+		{"iso", []string{"iso_lt"}, "<", ""},
+		{"iso", []string{"iso_lt"}, ",", ","},
+		{"iso", []string{"iso_lt"}, "\\", "iso_hash"},
+		{"jis", []string{"jis_yen", "\\", "jis_ro"}, "\\", ""},
+		{"jis", []string{"jis_yen", "\\", "jis_ro"}, "_", ""},
+		{"jis", []string{"jis_yen", "\\", "jis_ro"}, "-", "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout+" "+tt.press, func(t *testing.T) {
+			k := newKeyboardTest(KeyboardTestConfig{Layout: tt.layout}, "")
+			if !slices.Equal(k.unverifiable, tt.unverifiable) {
+				t.Errorf("unverifiable = %q, want %q", k.unverifiable, tt.unverifiable)
+			}
+
+			k.press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tt.press)})
+			var marked []string
+			for id := range k.seen {
+				marked = append(marked, id)
+			}
+			var want []string
+			if tt.want != "" {
+				want = []string{tt.want}
+			}
+			if !slices.Equal(marked, want) {
+				t.Errorf("press %q marked %q, want %q", tt.press, marked, want)
+			}
+		})
+	}
+}
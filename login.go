@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Кто и где проверяет устройство
+type operatorSession struct {
+	Station  string    `json:"station"`
+	Operator string    `json:"operator"`
+	LoginAt  time.Time `json:"login_at"`
+}
+
+// Оператор вошел, можно собирать информацию
+type operatorLoginMsg struct {
+	session operatorSession
+}
+
+func newLoginInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "ID оператора или бейдж"
+	ti.CharLimit = 64
+	ti.Width = 30
+	ti.Focus()
+	return ti
+}
+
+// ID оператора из введенной или отсканированной строки. Бейдж, набранный
+// в русской раскладке, исправляется так же, как серийный номер.
+func parseOperatorID(raw string, pattern string) (string, error) {
+	id, _ := correctCyrillicLayout(strings.TrimSpace(raw))
+	id = normalizeSerial(id)
+	if id == "" {
+		return "", fmt.Errorf("operator ID is empty")
+	}
+	// Формат проверен при загрузке конфигурации
+	if pattern != "" && !regexp.MustCompile(pattern).MatchString(id) {
+		return "", fmt.Errorf("operator ID %s does not match format %s", id, pattern)
+	}
+	return id, nil
+}
+
+// Вход оператора: первый этап конвейера, до сбора информации. Этап
+// завершается, когда информация собрана.
+type loginStage struct {
+	input    textinput.Model
+	err      string
+	loggedIn bool // Повторный ENTER не запускает сбор информации снова
+}
+
+func (loginStage) Name() string { return "login" }
+
+func (loginStage) Init(m model) (Stage, tea.Cmd) {
+	s := loginStage{input: newLoginInput()}
+	return s, s.input.Focus()
+}
+
+// Клавиши экрана входа. После входа начинается сбор информации.
+func (s loginStage) Update(m model, msg tea.Msg) (Stage, tea.Cmd) {
+	if s.loggedIn {
+		return s, nil
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c":
+			return s, tea.Quit
+
+		case "enter":
+			id, err := parseOperatorID(s.input.Value(), m.config.Station.OperatorPattern)
+			if err != nil {
+				s.err = err.Error()
+				s.input.SetValue("")
+				return s, nil
+			}
+			s.loggedIn = true
+			s.input.Blur()
+			session := operatorSession{
+				Station:  m.config.Station.ID,
+				Operator: id,
+				LoginAt:  time.Now(),
+			}
+			return s, func() tea.Msg { return operatorLoginMsg{session} }
+		}
+	}
+
+	var cmd tea.Cmd
+	s.input, cmd = s.input.Update(msg)
+	return s, cmd
+}
+
+// Экран входа оператора
+func (s loginStage) View(m model) string {
+	return m.overlayView(fmt.Sprintf(
+		"%s\n\n%s\n\n%s\n\n%s\n\n%s",
+		lipgloss.NewStyle().Bold(true).Render("Operator Login"),
+		fmt.Sprintf("Station: %s", m.config.Station.ID),
+		fmt.Sprintf("Enter or scan Operator ID: %s", s.input.View()),
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Render(s.err),
+		"[ENTER] Start   [CTRL+C] Quit",
+	))
+}
+
+// Вход не входит в итоги этапов, станция и оператор записываются в отчет отдельно
+func (loginStage) Result(m model) StageResult {
+	return StageResult{}
+}
+
+// Станция, оператор и заказ для заголовка
+func (m model) sessionLine() string {
+	var parts []string
+	if m.session.Operator != "" {
+		parts = append(parts, "Station "+m.session.Station, "Operator "+m.session.Operator)
+	}
+	if manifest := m.unitManifest(); manifest.found {
+		order := "Order " + manifest.entry.Order
+		if manifest.entry.Customer != "" {
+			order += " · " + manifest.entry.Customer
+		}
+		parts = append(parts, order)
+	}
+	return strings.Join(parts, "   ")
+}
+
+// Заголовок экрана: название и, после входа, станция и оператор
+func (m model) title() string {
+	if line := m.sessionLine(); line != "" {
+		return "TROUBADOUR   " + line
+	}
+	return "TROUBADOUR"
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
@@ -20,12 +22,20 @@ import (
 
 // Структуры для хранения данных системы
 type SystemInfo struct {
-	Processor    ProcessorInfo
-	Memory       MemoryInfo
-	Network      []NetworkInfo
-	GPU          GPUInfo
-	Storage      []StorageInfo
-	SerialNumber string
+	Processor       ProcessorInfo
+	Memory          MemoryInfo
+	Network         []NetworkInfo
+	GPU             GPUInfo
+	Storage         []StorageInfo
+	Power           PowerInfo
+	Sensors         SensorsInfo
+	Audio           AudioInfo
+	Manufacturer    string
+	ProductName     string
+	SerialNumber    string
+	BaseboardSerial string // Серийный номер материнской платы
+	ChassisSerial   string // Серийный номер корпуса
+	UUID            string // UUID системы из DMI
 }
 
 type ProcessorInfo struct {
@@ -63,84 +73,88 @@ type GPUInfo struct {
 	Architecture  string
 	Resolution    string
 	OpenGLVersion string
+
+	PanelConnector  string // Разъем встроенной панели в DRM (card0-eDP-1)
+	PanelResolution string // Родной режим встроенной панели
 }
 
 type StorageInfo struct {
-	Type  string // NVMe, SATA, USB, etc.
-	Model string
-	Size  string
-	Label string
+	Name        string // Имя блочного устройства (sda, nvme0n1)
+	DeviceType  string // Тип из lsblk TYPE (disk, rom)
+	Type        string // NVMe, SATA, USB, etc.
+	Model       string
+	Size        string
+	Label       string
+	Serial      string
+	WWN         string
+	Firmware    string
+	Transport   string   // Транспорт из lsblk TRAN (sata, nvme, usb)
+	Rotational  bool     // HDD (true) или SSD (false)
+	LogicalSec  string   // Размер логического сектора, байт
+	PhysicalSec string   // Размер физического сектора, байт
+	Removable   bool     // Съемный носитель
+	Mountpoints []string // Точки монтирования диска и его разделов
 }
 
 // Модели для TUI
 type model struct {
-	state             int // Состояние программы
+	config            StationConfig
+	pipeline          []Stage // Этапы проверки по порядку
+	stage             int     // Номер текущего этапа
+	resumeStage       int     // Этап, к которому вернуться с экрана информации
+	resumeInit        bool    // Запустить этап заново при возврате
+	collecting        bool    // Идет сбор системной информации
 	sysInfo           SystemInfo
 	width             int
 	height            int
-	textInput         textinput.Model
 	spinner           spinner.Model
 	viewport          viewport.Model
 	err               error
-	userSerial        string
 	dmidecodeRaw      string
-	logFilePath       string
-	showOverlay       bool      // Показывать ли наложение
-	overlayContent    string    // Содержимое наложения
-	videoTestActive   bool      // Активен ли видеотест
-	videoTestColor    int       // Текущий цвет видеотеста (0-red, 1-green, 2-blue, 3-testbars)
-	videoTestStart    time.Time // Время начала видеотеста
-	testPassed        bool      // Прошел ли видеотест успешно
-	serialMatched     bool      // Совпал ли серийный номер
-	logoAnimState     int       // Состояние анимации логотипа
-	progressAnimState int       // Состояние анимации прогресса
+	terminalColors    TerminalColors  // Цветовой профиль терминала
+	manifest          manifestState   // Заказ устройства по DMI из манифеста
+	session           operatorSession // Станция и оператор для заголовка и отчетов
+	logoAnimState     int             // Состояние анимации логотипа
+	progressAnimState int             // Состояние анимации прогресса
+	stageTimes        []stageTiming   // Время и повторы этапов конвейера
+	stageNotes        [][]StageNote   // Заметки оператора по этапам конвейера
+	stageNote         stageNoteState  // Открытое окно ввода заметки
+	generalNote       string          // Общая заметка к отчету
 }
 
-// Состояния программы
-const (
-	stateInit = iota
-	stateShowInfo
-	stateVideoTest
-	stateAskVideoOk
-	stateAskSerial
-	stateCheckSerial
-	stateSerialSuccess // Новое состояние для успешной проверки серийного номера
-	stateSerialError
-	stateCreateLogs
-	stateDone
-)
-
-func initialModel() model {
-	ti := textinput.New()
-	ti.Placeholder = "Введите серийный номер"
-	ti.Focus()
-	ti.CharLimit = 30
-	ti.Width = 30
-
+func initialModel(cfg StationConfig) model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 
 	vp := viewport.New(80, 20)
 
-	return model{
-		state:             stateInit,
-		textInput:         ti,
+	// Конфигурация проверена при загрузке, поэтому ошибки здесь быть не может
+	pipeline, _ := buildPipeline(cfg)
+
+	m := model{
+		config:            cfg,
+		pipeline:          pipeline,
+		stageTimes:        make([]stageTiming, len(pipeline)),
+		stageNotes:        make([][]StageNote, len(pipeline)),
 		spinner:           s,
 		viewport:          vp,
-		showOverlay:       false,
-		videoTestColor:    0,
+		terminalColors:    detectTerminalColors(),
 		logoAnimState:     0,
 		progressAnimState: 0,
 	}
+
+	// Проверка начинается с входа оператора
+	next, _ := m.startStage()
+	return next.(model)
 }
 
 func (m model) Init() tea.Cmd {
-	// Проверяем root и начинаем сбор данных
+	// Проверяем root; сбор данных начнется после входа оператора
 	return tea.Batch(
 		checkRootCmd,
 		spinner.Tick,
-		collectSystemInfoCmd,
+		textinput.Blink,
 		updateLogoAnimationCmd,
 	)
 }
@@ -158,7 +172,13 @@ type errMsg struct {
 }
 
 // Команды для сбора системной информации
-func collectSystemInfoCmd() tea.Msg {
+func collectSystemInfoCmd(cfg StationConfig) tea.Cmd {
+	return func() tea.Msg {
+		return collectSystemInfo(cfg)
+	}
+}
+
+func collectSystemInfo(cfg StationConfig) tea.Msg {
 	sysInfo := SystemInfo{}
 	var err error
 
@@ -186,12 +206,30 @@ func collectSystemInfoCmd() tea.Msg {
 		return errMsg{err}
 	}
 
+	// xrandr на консоли ничего не возвращает, режим панели берем из DRM
+	sysInfo.GPU.PanelConnector, sysInfo.GPU.PanelResolution = getPanelMode()
+
 	// Получение информации о накопителях
 	sysInfo.Storage, err = getStorageInfo()
 	if err != nil {
 		return errMsg{err}
 	}
 
+	// Получение информации о батарее и питании
+	sysInfo.Power, err = getPowerInfo(cfg.Battery.MaxWearPercent)
+	if err != nil {
+		return errMsg{err}
+	}
+
+	// Получение информации о звуковых картах
+	sysInfo.Audio, err = getAudioInfo(cfg.Audio.ExpectedCodecs)
+	if err != nil {
+		return errMsg{err}
+	}
+
+	// Получение показаний датчиков hwmon
+	sysInfo.Sensors = getSensorsInfo()
+
 	// Получение серийного номера из dmidecode
 	dmidecodeRaw, err := execCommand("dmidecode", "-t", "system")
 	if err != nil {
@@ -204,6 +242,26 @@ func collectSystemInfoCmd() tea.Msg {
 		sysInfo.SerialNumber = strings.TrimSpace(matches[1])
 	}
 
+	// Производитель и модель нужны для выбора настроек под конкретное устройство
+	if matches := regexp.MustCompile(`Manufacturer:\s*(.+)`).FindStringSubmatch(dmidecodeRaw); len(matches) > 1 {
+		sysInfo.Manufacturer = strings.TrimSpace(matches[1])
+	}
+	if matches := regexp.MustCompile(`Product Name:\s*(.+)`).FindStringSubmatch(dmidecodeRaw); len(matches) > 1 {
+		sysInfo.ProductName = strings.TrimSpace(matches[1])
+	}
+	if matches := regexp.MustCompile(`UUID:\s*(.+)`).FindStringSubmatch(dmidecodeRaw); len(matches) > 1 {
+		sysInfo.UUID = strings.TrimSpace(matches[1])
+	}
+
+	// Серийные номера платы и корпуса нужны только для сверки с наклейкой,
+	// поэтому их отсутствие не считается ошибкой
+	if out, err := execCommand("dmidecode", "-s", "baseboard-serial-number"); err == nil {
+		sysInfo.BaseboardSerial = strings.TrimSpace(out)
+	}
+	if out, err := execCommand("dmidecode", "-s", "chassis-serial-number"); err == nil {
+		sysInfo.ChassisSerial = strings.TrimSpace(out)
+	}
+
 	return sysInfoCollectedMsg{
 		sysInfo:      sysInfo,
 		dmidecodeRaw: dmidecodeRaw,
@@ -480,6 +538,33 @@ func getNetworkInfo() ([]NetworkInfo, error) {
 	return interfaces, nil
 }
 
+// Разъемы встроенной панели в DRM; внешние мониторы (HDMI, DP) не учитываются
+var panelConnectorRe = regexp.MustCompile(`^card\d+-(eDP|LVDS|DSI)-\d+$`)
+
+// Родной режим встроенной панели: первая строка modes подключенного разъема
+// eDP/LVDS/DSI. Пустой результат - панель не найдена.
+func getPanelMode() (string, string) {
+	connectors, _ := filepath.Glob("/sys/class/drm/card*")
+	for _, dir := range connectors {
+		name := filepath.Base(dir)
+		if !panelConnectorRe.MatchString(name) {
+			continue
+		}
+		status, err := os.ReadFile(filepath.Join(dir, "status"))
+		if err != nil || strings.TrimSpace(string(status)) != "connected" {
+			continue
+		}
+		modes, err := os.ReadFile(filepath.Join(dir, "modes"))
+		if err != nil {
+			continue
+		}
+		if mode, _, _ := strings.Cut(strings.TrimSpace(string(modes)), "\n"); mode != "" {
+			return name, mode
+		}
+	}
+	return "", ""
+}
+
 func getGPUInfo() (GPUInfo, error) {
 	var info GPUInfo
 
@@ -586,127 +671,236 @@ func getGPUInfo() (GPUInfo, error) {
 	return info, nil
 }
 
+// Колонки lsblk, которые нужны для описания накопителя
+const lsblkColumns = "NAME,SIZE,TYPE,MODEL,MOUNTPOINT,LABEL,SERIAL,WWN,REV,TRAN,ROTA,LOG-SEC,PHY-SEC,RM"
+
+// Префиксы имен виртуальных блочных устройств, которые не являются дисками
+var virtualBlockPrefixes = []string{"zram", "loop", "ram", "dm-", "md", "nbd"}
+
+// lsblkValue принимает значения lsblk в любом виде: строка, число, bool или null.
+// Старые версии util-linux выводят все поля строками, новые - типизированно.
+type lsblkValue string
+
+func (v *lsblkValue) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		*v = ""
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	*v = lsblkValue(strings.TrimSpace(s))
+	return nil
+}
+
+func (v lsblkValue) String() string {
+	return string(v)
+}
+
+// Bool интерпретирует значение как флаг lsblk (1/0, true/false)
+func (v lsblkValue) Bool() bool {
+	return v == "1" || v == "true"
+}
+
+type lsblkDevice struct {
+	Name       lsblkValue    `json:"name"`
+	Size       lsblkValue    `json:"size"`
+	Type       lsblkValue    `json:"type"`
+	Model      lsblkValue    `json:"model"`
+	Mountpoint lsblkValue    `json:"mountpoint"`
+	Label      lsblkValue    `json:"label"`
+	Serial     lsblkValue    `json:"serial"`
+	WWN        lsblkValue    `json:"wwn"`
+	Rev        lsblkValue    `json:"rev"`
+	Tran       lsblkValue    `json:"tran"`
+	Rota       lsblkValue    `json:"rota"`
+	LogSec     lsblkValue    `json:"log-sec"`
+	PhySec     lsblkValue    `json:"phy-sec"`
+	RM         lsblkValue    `json:"rm"`
+	Children   []lsblkDevice `json:"children,omitempty"`
+}
+
+// isVirtualBlockDevice определяет zram, loop, device-mapper, md и подобные устройства
+func isVirtualBlockDevice(name string) bool {
+	for _, prefix := range virtualBlockPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	// У реальных дисков в sysfs есть ссылка на родительское устройство
+	if _, err := os.Stat(filepath.Join("/sys/block", name)); err == nil {
+		if _, err := os.Stat(filepath.Join("/sys/block", name, "device")); os.IsNotExist(err) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// storageTypeFor определяет тип накопителя по транспорту lsblk и имени устройства
+func storageTypeFor(name, tran string) string {
+	switch strings.ToLower(tran) {
+	case "nvme":
+		return "NVMe"
+	case "usb":
+		return "USB"
+	case "mmc":
+		return "SD/MMC"
+	case "sata", "ata", "pata", "sas", "scsi":
+		return "SATA/IDE"
+	}
+
+	// Старые версии lsblk не всегда заполняют TRAN
+	if strings.HasPrefix(name, "nvme") {
+		return "NVMe"
+	} else if strings.HasPrefix(name, "sd") {
+		// Проверяем, USB это или SATA
+		realPath, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/block/%s", name))
+		if err == nil && strings.Contains(realPath, "usb") {
+			return "USB"
+		}
+	} else if strings.HasPrefix(name, "mmcblk") {
+		return "SD/MMC"
+	} else if strings.HasPrefix(name, "sr") {
+		return "Optical"
+	}
+
+	return "SATA/IDE"
+}
+
+// newStorageInfo собирает описание накопителя из записи lsblk
+func newStorageInfo(device lsblkDevice) StorageInfo {
+	storage := StorageInfo{
+		Name:        device.Name.String(),
+		DeviceType:  device.Type.String(),
+		Type:        storageTypeFor(device.Name.String(), device.Tran.String()),
+		Model:       device.Model.String(),
+		Size:        device.Size.String(),
+		Label:       device.Label.String(),
+		Serial:      device.Serial.String(),
+		WWN:         device.WWN.String(),
+		Firmware:    device.Rev.String(),
+		Transport:   device.Tran.String(),
+		Rotational:  device.Rota.Bool(),
+		LogicalSec:  device.LogSec.String(),
+		PhysicalSec: device.PhySec.String(),
+		Removable:   device.RM.Bool(),
+	}
+
+	// TRAN у оптического привода - sata или usb, тип берем из lsblk TYPE
+	if storage.DeviceType == "rom" {
+		storage.Type = "Optical"
+	}
+
+	if device.Mountpoint != "" {
+		storage.Mountpoints = append(storage.Mountpoints, device.Mountpoint.String())
+	}
+
+	// Ищем метку и точки монтирования в разделах
+	for _, partition := range device.Children {
+		if storage.Label == "" && partition.Label != "" {
+			storage.Label = partition.Label.String()
+		}
+		if partition.Mountpoint != "" {
+			storage.Mountpoints = append(storage.Mountpoints, partition.Mountpoint.String())
+		}
+	}
+
+	return storage
+}
+
 func getStorageInfo() ([]StorageInfo, error) {
 	var storageDevices []StorageInfo
+	var devices []lsblkDevice
 
 	// Используем lsblk для получения информации о дисках
-	cmd := exec.Command("sh", "-c", "lsblk -o NAME,SIZE,TYPE,MODEL,MOUNTPOINT,LABEL -J")
+	cmd := exec.Command("lsblk", "-J", "-o", lsblkColumns)
 	output, err := cmd.Output()
-	if err != nil {
+	if err == nil {
+		// Парсим JSON от lsblk
+		var lsblkOutput struct {
+			Blockdevices []lsblkDevice `json:"blockdevices"`
+		}
+
+		err = json.Unmarshal(output, &lsblkOutput)
+		if err != nil {
+			return storageDevices, err
+		}
+		devices = lsblkOutput.Blockdevices
+	} else {
 		// Попробуем альтернативный вариант без -J (JSON форматирования)
-		cmd = exec.Command("sh", "-c", "lsblk -o NAME,SIZE,TYPE,MODEL,MOUNTPOINT,LABEL")
-		output, err = cmd.Output()
+		devices, err = getStorageInfoPairs()
 		if err != nil {
 			return storageDevices, err
 		}
+	}
 
-		// Парсим текстовый вывод lsblk
-		lines := strings.Split(string(output), "\n")
-		if len(lines) > 1 { // Пропускаем заголовок
-			for i := 1; i < len(lines); i++ {
-				fields := strings.Fields(lines[i])
-				if len(fields) >= 3 && fields[2] == "disk" {
-					device := StorageInfo{
-						Type: "SATA/IDE",
-						Size: fields[1],
-					}
-
-					if len(fields) >= 4 {
-						device.Model = fields[3]
-					}
-
-					if strings.HasPrefix(fields[0], "nvme") {
-						device.Type = "NVMe"
-					} else if strings.HasPrefix(fields[0], "sd") {
-						// Проверяем, USB это или SATA
-						symlinkPath := fmt.Sprintf("/sys/block/%s", fields[0])
-						realPath, err := filepath.EvalSymlinks(symlinkPath)
-						if err == nil {
-							if strings.Contains(realPath, "usb") {
-								device.Type = "USB"
-							}
-						}
-					} else if strings.HasPrefix(fields[0], "mmcblk") {
-						device.Type = "SD/MMC"
-					}
-
-					// Ищем метку в выводе lsblk
-					if len(fields) >= 6 {
-						device.Label = fields[5]
-					}
+	// Обрабатываем полученные данные
+	for _, device := range devices {
+		if device.Type != "disk" && device.Type != "rom" {
+			continue
+		}
 
-					storageDevices = append(storageDevices, device)
-				}
-			}
+		// Пропускаем zram, loop и прочие виртуальные устройства
+		if isVirtualBlockDevice(device.Name.String()) {
+			continue
 		}
 
-		return storageDevices, nil
+		storageDevices = append(storageDevices, newStorageInfo(device))
 	}
 
-	// Парсим JSON от lsblk
-	var lsblkOutput struct {
-		Blockdevices []struct {
-			Name       string `json:"name"`
-			Size       string `json:"size"`
-			Type       string `json:"type"`
-			Model      string `json:"model"`
-			Mountpoint string `json:"mountpoint"`
-			Label      string `json:"label"`
-			Children   []struct {
-				Name       string `json:"name"`
-				Size       string `json:"size"`
-				Type       string `json:"type"`
-				Mountpoint string `json:"mountpoint"`
-				Label      string `json:"label"`
-			} `json:"children,omitempty"`
-		} `json:"blockdevices"`
-	}
+	return storageDevices, nil
+}
 
-	err = json.Unmarshal(output, &lsblkOutput)
+// getStorageInfoPairs читает lsblk в формате KEY="value" для систем без поддержки -J.
+// Разделы в этом формате идут отдельными строками после своего диска.
+func getStorageInfoPairs() ([]lsblkDevice, error) {
+	output, err := exec.Command("lsblk", "-P", "-o", lsblkColumns).Output()
 	if err != nil {
-		return storageDevices, err
+		return nil, err
 	}
 
-	// Обрабатываем полученные данные
-	for _, device := range lsblkOutput.Blockdevices {
-		if device.Type == "disk" || device.Type == "rom" {
-			storageType := "SATA/IDE"
-
-			// Определяем тип устройства (NVMe, USB, и т.д.)
-			if strings.HasPrefix(device.Name, "nvme") {
-				storageType = "NVMe"
-			} else if strings.HasPrefix(device.Name, "sd") {
-				// Проверяем, USB это или SATA
-				symlinkPath := fmt.Sprintf("/sys/block/%s", device.Name)
-				realPath, err := filepath.EvalSymlinks(symlinkPath)
-				if err == nil {
-					if strings.Contains(realPath, "usb") {
-						storageType = "USB"
-					}
-				}
-			} else if strings.HasPrefix(device.Name, "mmcblk") {
-				storageType = "SD/MMC"
-			}
+	pairRegex := regexp.MustCompile(`([A-Z-]+)="([^"]*)"`)
+	var devices []lsblkDevice
 
-			storage := StorageInfo{
-				Type:  storageType,
-				Model: device.Model,
-				Size:  device.Size,
-			}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := make(map[string]lsblkValue)
+		for _, pair := range pairRegex.FindAllStringSubmatch(line, -1) {
+			fields[pair[1]] = lsblkValue(strings.TrimSpace(pair[2]))
+		}
+		if fields["NAME"] == "" {
+			continue
+		}
 
-			// Ищем метку в разделах, если она есть
-			for _, partition := range device.Children {
-				if partition.Label != "" {
-					storage.Label = partition.Label
-					break
-				}
-			}
+		device := lsblkDevice{
+			Name:       fields["NAME"],
+			Size:       fields["SIZE"],
+			Type:       fields["TYPE"],
+			Model:      fields["MODEL"],
+			Mountpoint: fields["MOUNTPOINT"],
+			Label:      fields["LABEL"],
+			Serial:     fields["SERIAL"],
+			WWN:        fields["WWN"],
+			Rev:        fields["REV"],
+			Tran:       fields["TRAN"],
+			Rota:       fields["ROTA"],
+			LogSec:     fields["LOG-SEC"],
+			PhySec:     fields["PHY-SEC"],
+			RM:         fields["RM"],
+		}
 
-			storageDevices = append(storageDevices, storage)
+		if device.Type == "part" && len(devices) > 0 {
+			parent := &devices[len(devices)-1]
+			parent.Children = append(parent.Children, device)
+			continue
 		}
+
+		devices = append(devices, device)
 	}
 
-	return storageDevices, nil
+	return devices, nil
 }
 
 // Вспомогательная функция для выполнения команд
@@ -719,36 +913,29 @@ func execCommand(command string, args ...string) (string, error) {
 	return string(output), nil
 }
 
-// Команда для запуска видео теста в терминале (без ffplay)
-func startVideoTestCmd() tea.Msg {
-	return startVideoTestMsg{}
-}
-
-type startVideoTestMsg struct{}
-
-type videoTestTimerTickMsg struct{}
-
-// Окончание видеотеста
-type videoTestDoneMsg struct{}
-
 // Перезапуск системной информации
 type restartSystemInfoMsg struct{}
 
-// Проверка серийного номера
-func checkSerialNumberCmd(entered, system string) tea.Msg {
-	if entered == system {
-		return serialMatchedMsg{}
+// Проверка серийного номера по полям DMI из конфигурации
+func checkSerialNumberCmd(entered string, info SystemInfo, cfg SerialConfig) tea.Msg {
+	match, err := matchSerial(entered, info, cfg)
+	if err == nil {
+		return serialMatchedMsg{match}
 	}
 	return serialMismatchMsg{
 		entered: entered,
-		system:  system,
+		system:  info.SerialNumber,
+		reason:  err.Error(),
 	}
 }
 
-type serialMatchedMsg struct{}
+type serialMatchedMsg struct {
+	match serialMatch
+}
 type serialMismatchMsg struct {
 	entered string
 	system  string
+	reason  string // Почему номер не принят
 }
 
 // Перезапустить компьютер
@@ -757,25 +944,40 @@ type restartMsg struct{}
 // Выключить компьютер
 type shutdownMsg struct{}
 
+// Каталог отчетов
+const logsDir = "./troubadour_logs"
+
 // Команда для создания логов
-func createLogFilesCmd(info SystemInfo, dmidecodeRaw string, testPassed bool, serialMatched bool) tea.Msg {
+func createLogFilesCmd(report unitReport) tea.Msg {
+	info, serial, stages, note := report.Info, report.SerialCheck, report.Stages, report.Note
+	video, storageResults := report.Video, report.StorageResults
+	keyboard, pointer, backlight, camera := report.Keyboard, report.Pointer, report.Backlight, report.Camera
+
 	// Создаем директорию для логов
-	logsDir := "./troubadour_logs"
 	err := os.MkdirAll(logsDir, 0755)
 	if err != nil {
 		return errMsg{err}
 	}
 
-	// Создаем имя файла с датой и серийным номером
-	timestamp := time.Now().Format("20060102_150405")
-	fileName := fmt.Sprintf("%s/troubadour_%s_%s.log", logsDir, info.SerialNumber, timestamp)
+	// Имя файла по шаблону из конфигурации
+	fileName := filepath.Join(logsDir, report.BaseName+".log")
 
 	// Форматируем содержимое лога
 	var logContent strings.Builder
 
 	logContent.WriteString("==== TROUBADOUR SYSTEM DIAGNOSTICS LOG ====\n\n")
-	logContent.WriteString(fmt.Sprintf("Date: %s\n", time.Now().Format(time.RFC1123)))
-	logContent.WriteString(fmt.Sprintf("Serial Number: %s\n\n", info.SerialNumber))
+	logContent.WriteString(fmt.Sprintf("Date: %s\n", report.Date.Format(time.RFC1123)))
+	logContent.WriteString(fmt.Sprintf("Station: %s\n", report.Station))
+	logContent.WriteString(fmt.Sprintf("Operator: %s (logged in %s)\n", report.Operator, report.LoginAt.Format("2006-01-02 15:04:05")))
+	if note := serial.sourceNote(); note != "" {
+		logContent.WriteString(fmt.Sprintf("Serial Number: %s (%s, DMI: %q)\n\n", serial.Unit, note, info.SerialNumber))
+	} else {
+		logContent.WriteString(fmt.Sprintf("Serial Number: %s\n\n", serial.Unit))
+	}
+	if serial.Order != nil {
+		logContent.WriteString(fmt.Sprintf("Work Order: %s\n", serial.Order.Order))
+		logContent.WriteString(fmt.Sprintf("Customer: %s\n\n", serial.Order.Customer))
+	}
 
 	// Информация о процессоре
 	logContent.WriteString("==== PROCESSOR ====\n")
@@ -825,6 +1027,9 @@ func createLogFilesCmd(info SystemInfo, dmidecodeRaw string, testPassed bool, se
 	if info.GPU.Resolution != "" {
 		logContent.WriteString(fmt.Sprintf("Resolution: %s\n", info.GPU.Resolution))
 	}
+	if info.GPU.PanelResolution != "" {
+		logContent.WriteString(fmt.Sprintf("Panel: %s (%s)\n", info.GPU.PanelResolution, info.GPU.PanelConnector))
+	}
 	if info.GPU.OpenGLVersion != "" {
 		logContent.WriteString(fmt.Sprintf("OpenGL Version: %s\n", info.GPU.OpenGLVersion))
 	}
@@ -833,25 +1038,232 @@ func createLogFilesCmd(info SystemInfo, dmidecodeRaw string, testPassed bool, se
 	// Информация о накопителях
 	logContent.WriteString("==== STORAGE ====\n")
 	for _, storage := range info.Storage {
+		logContent.WriteString(fmt.Sprintf("Device: /dev/%s\n", storage.Name))
 		logContent.WriteString(fmt.Sprintf("Type: %s\n", storage.Type))
 		logContent.WriteString(fmt.Sprintf("Model: %s\n", storage.Model))
 		logContent.WriteString(fmt.Sprintf("Size: %s\n", storage.Size))
+		if storage.Serial != "" {
+			logContent.WriteString(fmt.Sprintf("Serial: %s\n", storage.Serial))
+		}
+		if storage.WWN != "" {
+			logContent.WriteString(fmt.Sprintf("WWN: %s\n", storage.WWN))
+		}
+		if storage.Firmware != "" {
+			logContent.WriteString(fmt.Sprintf("Firmware: %s\n", storage.Firmware))
+		}
+		if storage.Transport != "" {
+			logContent.WriteString(fmt.Sprintf("Transport: %s\n", storage.Transport))
+		}
+		logContent.WriteString(fmt.Sprintf("Rotational: %t\n", storage.Rotational))
+		logContent.WriteString(fmt.Sprintf("Sector Size: %s logical / %s physical\n", storage.LogicalSec, storage.PhysicalSec))
+		logContent.WriteString(fmt.Sprintf("Removable: %t\n", storage.Removable))
 		if storage.Label != "" {
 			logContent.WriteString(fmt.Sprintf("Label: %s\n", storage.Label))
 		}
 		logContent.WriteString("\n")
 	}
 
-	// Информация о пройденных этапах
+	// Информация о батарее
+	if len(info.Power.Batteries) > 0 {
+		logContent.WriteString("==== BATTERY ====\n")
+		for _, battery := range info.Power.Batteries {
+			logContent.WriteString(fmt.Sprintf("Battery: %s\n", battery.Name))
+			logContent.WriteString(fmt.Sprintf("Manufacturer: %s\n", battery.Manufacturer))
+			logContent.WriteString(fmt.Sprintf("Model: %s\n", battery.Model))
+			logContent.WriteString(fmt.Sprintf("Technology: %s\n", battery.Technology))
+			logContent.WriteString(fmt.Sprintf("Capacity: %s\n", battery.capacityString()))
+			logContent.WriteString(fmt.Sprintf("Wear: %.1f%%\n", battery.WearPercent))
+			logContent.WriteString(fmt.Sprintf("Cycle Count: %d\n", battery.CycleCount))
+			logContent.WriteString(fmt.Sprintf("Status: %s\n\n", battery.Status))
+		}
+		logContent.WriteString(fmt.Sprintf("AC Adapter: %s\n\n", info.Power.acState()))
+	}
+
+	// Информация о звуке
+	logContent.WriteString("==== AUDIO ====\n")
+	for _, card := range info.Audio.Cards {
+		logContent.WriteString(fmt.Sprintf("Card %s: %s [%s]\n", card.Index, card.Name, card.ID))
+		logContent.WriteString(fmt.Sprintf("Driver: %s\n", card.Driver))
+		for _, codec := range card.Codecs {
+			logContent.WriteString(fmt.Sprintf("Codec: %s (%s)\n", codec.Name, codec.VendorID))
+		}
+		for _, pcm := range card.PCMs {
+			logContent.WriteString(fmt.Sprintf("PCM %s: %s [%s]\n", pcm.Device, pcm.Name, pcm.directions()))
+		}
+		logContent.WriteString("\n")
+	}
+	if len(info.Audio.Cards) == 0 {
+		logContent.WriteString("No sound cards detected\n\n")
+	}
+
+	// Снимок показаний датчиков
+	if info.Sensors.Warning != "" {
+		logContent.WriteString(fmt.Sprintf("Sensors read error: %s\n\n", info.Sensors.Warning))
+	}
+	if len(info.Sensors.Chips) > 0 {
+		logContent.WriteString("==== SENSORS ====\n")
+		logContent.WriteString(fmt.Sprintf("Snapshot: %s\n", info.Sensors.ReadAt.Format(time.RFC1123)))
+		for _, chip := range info.Sensors.Chips {
+			logContent.WriteString(fmt.Sprintf("Chip: %s\n", chip.Name))
+			for _, sensor := range chip.Sensors {
+				line := "  " + sensor.String()
+				if sensor.Critical {
+					line += " [CRITICAL]"
+				}
+				logContent.WriteString(line + "\n")
+			}
+		}
+		logContent.WriteString("\n")
+	}
+
+	// Результаты проверки чтения накопителей
+	if len(storageResults) > 0 {
+		logContent.WriteString("==== STORAGE READ TEST ====\n")
+		for _, res := range storageResults {
+			logContent.WriteString(fmt.Sprintf("Device: %s %s\n", res.Device, res.Model))
+			if res.Skipped != "" {
+				logContent.WriteString(fmt.Sprintf("Skipped: %s\n\n", res.Skipped))
+				continue
+			}
+			logContent.WriteString(fmt.Sprintf("Read: %d of %d bytes in %s\n", res.BytesRead, res.SizeBytes, res.Duration.Round(time.Millisecond)))
+			logContent.WriteString(fmt.Sprintf("Throughput: %.1f MB/s\n", res.ThroughputMBs))
+			logContent.WriteString(fmt.Sprintf("Latency: p50 %s, p95 %s, p99 %s\n", res.LatencyP50, res.LatencyP95, res.LatencyP99))
+			for _, readErr := range res.ReadErrors {
+				logContent.WriteString(fmt.Sprintf("Read Error: %s\n", readErr))
+			}
+			logContent.WriteString(fmt.Sprintf("Passed: %t\n\n", res.Passed))
+		}
+	}
+
+	// Вердикты по шаблонам видеотеста
+	logContent.WriteString("==== VIDEO TEST ====\n")
+	logContent.WriteString(fmt.Sprintf("Terminal Color Profile: %s\n", video.Terminal))
+	for _, pattern := range video.Patterns {
+		switch {
+		case pattern.Failed:
+			logContent.WriteString(fmt.Sprintf("%s: FAILED - %s\n", pattern.Name, pattern.Reason))
+		case pattern.Shown:
+			logContent.WriteString(fmt.Sprintf("%s: OK\n", pattern.Name))
+		default:
+			logContent.WriteString(fmt.Sprintf("%s: NOT SHOWN\n", pattern.Name))
+		}
+	}
+	logContent.WriteString("\n")
+
+	// Результаты теста клавиатуры
+	if keyboard != nil {
+		logContent.WriteString("==== KEYBOARD TEST ====\n")
+		logContent.WriteString(fmt.Sprintf("Layout: %s\n", keyboard.Layout))
+		logContent.WriteString(fmt.Sprintf("Keys Pressed: %d of %d\n", keyboard.Seen, keyboard.Total))
+		if len(keyboard.Unsided) > 0 {
+			logContent.WriteString(fmt.Sprintf("Left/Right Not Tested Separately: %s\n", strings.Join(keyboard.Unsided, " ")))
+		}
+		if len(keyboard.Unverifiable) > 0 {
+			logContent.WriteString(fmt.Sprintf("Not Distinguishable by Terminal: %s\n", strings.Join(keyboard.Unverifiable, " ")))
+		}
+		if len(keyboard.Missing) > 0 {
+			logContent.WriteString(fmt.Sprintf("Never Pressed: %s\n", strings.Join(keyboard.Missing, " ")))
+		}
+		logContent.WriteString(fmt.Sprintf("Marked Failed by Operator: %t\n\n", keyboard.Failed))
+	}
+
+	// Результаты теста указателя
+	if pointer != nil {
+		logContent.WriteString("==== POINTER TEST ====\n")
+		for _, action := range pointer.Actions {
+			if action.Done {
+				logContent.WriteString(fmt.Sprintf("%s: done at %s\n", action.Name, action.DoneAt.Format("15:04:05")))
+			} else {
+				logContent.WriteString(fmt.Sprintf("%s: NOT DONE\n", action.Name))
+			}
+		}
+		logContent.WriteString(fmt.Sprintf("Path Coverage: %.0f%%\n", pointer.PathCoverage))
+		logContent.WriteString(fmt.Sprintf("Marked Failed by Operator: %t\n\n", pointer.Failed))
+	}
+
+	// Результаты теста подсветки
+	if backlight != nil {
+		logContent.WriteString("==== BACKLIGHT TEST ====\n")
+		if backlight.Error != "" {
+			logContent.WriteString(fmt.Sprintf("Error: %s\n", backlight.Error))
+		}
+		for _, device := range backlight.Devices {
+			steps := make([]string, len(device.Steps))
+			for i, level := range device.Steps {
+				steps[i] = strconv.Itoa(level)
+			}
+			logContent.WriteString(fmt.Sprintf("%s (%s): max %d, original %d\n", device.Name, device.Type, device.Max, device.Original))
+			logContent.WriteString(fmt.Sprintf("  Available Levels: %d\n", device.availableLevels()))
+			logContent.WriteString(fmt.Sprintf("  Tested Steps: %s\n", strings.Join(steps, " ")))
+			if device.Error != "" {
+				logContent.WriteString(fmt.Sprintf("  Write Error: %s\n", device.Error))
+			}
+		}
+		logContent.WriteString(fmt.Sprintf("Confirmed by Operator: %t\n\n", backlight.Confirmed))
+	}
+
+	// Результаты теста камеры
+	if camera != nil {
+		logContent.WriteString("==== CAMERA TEST ====\n")
+		if camera.Device != "" {
+			logContent.WriteString(fmt.Sprintf("Device: %s (%s, driver %s)\n", camera.Device, camera.Card, camera.Driver))
+			logContent.WriteString(fmt.Sprintf("Frame: %dx%d %s\n", camera.Width, camera.Height, camera.Format))
+		}
+		if camera.Error != "" {
+			logContent.WriteString(fmt.Sprintf("Error: %s\n", camera.Error))
+		} else {
+			logContent.WriteString(fmt.Sprintf("Mean Brightness: %.1f, StdDev: %.1f\n", camera.Mean, camera.StdDev))
+			logContent.WriteString(fmt.Sprintf("Not Black or Uniform: %t\n", camera.AutoCheck))
+		}
+		logContent.WriteString(fmt.Sprintf("Confirmed by Operator: %t\n\n", camera.Confirmed))
+	}
+
+	// Итоги этапов: статус, время, повторы, значения и причины
 	logContent.WriteString("==== TEST RESULTS ====\n")
-	logContent.WriteString(fmt.Sprintf("Video Test Passed: %t\n", testPassed))
-	logContent.WriteString(fmt.Sprintf("Serial Number Check: %t\n", serialMatched))
-	logContent.WriteString(fmt.Sprintf("Entered Serial Number: %s\n", info.SerialNumber))
+	for _, res := range stages {
+		logContent.WriteString(fmt.Sprintf("[%s] %s\n", res.Stage, strings.ToUpper(string(res.Status))))
+		if !res.Started.IsZero() {
+			logContent.WriteString(fmt.Sprintf("  Started: %s\n", res.Started.Format("2006-01-02 15:04:05")))
+		}
+		if !res.Finished.IsZero() {
+			logContent.WriteString(fmt.Sprintf("  Finished: %s (%s)\n", res.Finished.Format("2006-01-02 15:04:05"), res.Duration.Round(time.Second)))
+		}
+		if res.Retries > 0 {
+			logContent.WriteString(fmt.Sprintf("  Retries: %d\n", res.Retries))
+		}
+		for _, v := range res.Values {
+			logContent.WriteString(fmt.Sprintf("  %s: %s\n", v.Name, v.Value))
+		}
+		for _, reason := range res.Reasons {
+			logContent.WriteString(fmt.Sprintf("  - %s\n", reason))
+		}
+		for _, n := range res.Notes {
+			logContent.WriteString(fmt.Sprintf("  Note: %s\n", n))
+		}
+	}
+	logContent.WriteString(fmt.Sprintf("Verdict: %s\n", report.Verdict))
+	if note != "" {
+		logContent.WriteString(fmt.Sprintf("Operator Note: %s\n", note))
+	}
+	logContent.WriteString(fmt.Sprintf("Serial Number Check: %t\n", serial.Matched))
+	logContent.WriteString(fmt.Sprintf("Entered Serial Number: %s\n", serial.Entered))
 	logContent.WriteString(fmt.Sprintf("System Serial Number: %s\n\n", info.SerialNumber))
 
+	// Все попытки сверки, включая несовпадения
+	logContent.WriteString("==== SERIAL NUMBER ATTEMPTS ====\n")
+	for i, attempt := range serial.Attempts {
+		logContent.WriteString(fmt.Sprintf("%d. %s entered %q (raw %q): %s\n",
+			i+1, attempt.Time.Format("2006-01-02 15:04:05"), attempt.Entered, attempt.Raw, attempt.outcome()))
+	}
+	for _, override := range serial.Overrides {
+		logContent.WriteString(fmt.Sprintf("Supervisor Override: %s granted=%t after %d failed attempts\n",
+			override.Time.Format("2006-01-02 15:04:05"), override.Granted, override.Failures))
+	}
+	logContent.WriteString("\n")
+
 	// Добавляем сырой вывод dmidecode
 	logContent.WriteString("==== RAW DMIDECODE DATA ====\n")
-	logContent.WriteString(dmidecodeRaw)
+	logContent.WriteString(report.DMIDecodeRaw)
 
 	// Записываем лог в файл
 	err = os.WriteFile(fileName, []byte(logContent.String()), 0644)
@@ -859,13 +1271,103 @@ func createLogFilesCmd(info SystemInfo, dmidecodeRaw string, testPassed bool, se
 		return errMsg{err}
 	}
 
+	// Машиночитаемый отчет рядом с текстовым
+	jsonFileName := strings.TrimSuffix(fileName, ".log") + ".json"
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errMsg{err}
+	}
+	if err := os.WriteFile(jsonFileName, data, 0644); err != nil {
+		return errMsg{err}
+	}
+
 	return logCreatedMsg{
-		fileName: fileName,
+		fileName:     fileName,
+		jsonFileName: jsonFileName,
+	}
+}
+
+// Отчет об устройстве: JSON-отчет и данные для текстового лога
+type unitReport struct {
+	Serial       string               `json:"serial"`
+	SerialSource string               `json:"serial_source"` // dmi, dmi-<поле>, operator или entered
+	DMISerial    string               `json:"dmi_serial"`
+	Manufacturer string               `json:"manufacturer"`
+	Product      string               `json:"product"`
+	Date         time.Time            `json:"date"`
+	Station      string               `json:"station"`
+	Operator     string               `json:"operator"`
+	LoginAt      time.Time            `json:"login_at"`
+	Verdict      string               `json:"verdict"`
+	Note         string               `json:"note,omitempty"` // Общая заметка оператора
+	Stages       []StageResult        `json:"stages"`
+	Attempts     []SerialAttempt      `json:"serial_attempts"`
+	Overrides    []SupervisorOverride `json:"supervisor_overrides,omitempty"`
+	Order        *ManifestEntry       `json:"order,omitempty"`
+
+	// Данные только для текстового отчета
+	BaseName       string               `json:"-"` // Имя файлов отчета без расширения
+	Info           SystemInfo           `json:"-"`
+	DMIDecodeRaw   string               `json:"-"`
+	SerialCheck    serialReport         `json:"-"`
+	Video          VideoTestResult      `json:"-"`
+	StorageResults []DiskReadResult     `json:"-"`
+	Keyboard       *KeyboardTestResult  `json:"-"`
+	Pointer        *PointerTestResult   `json:"-"`
+	Backlight      *BacklightTestResult `json:"-"`
+	Camera         *CameraTestResult    `json:"-"`
+}
+
+// Данные сверки серийного номера для отчета
+type serialReport struct {
+	Unit      string // Серийный номер устройства для отчета и имени файла
+	Source    string // Откуда взят Unit, см. serialSource*
+	Matched   bool
+	Entered   string
+	Attempts  []SerialAttempt
+	Overrides []SupervisorOverride
+	Order     *ManifestEntry // Заказ из манифеста, если устройство принято
+}
+
+// Источник серийного номера устройства. При заглушке в DMI номер берется из
+// совпавшего поля DMI, с наклейки со слов оператора или, если сверка не
+// прошла, из ввода оператора.
+const (
+	serialSourceDMI      = "dmi"
+	serialSourceOperator = "operator"
+	serialSourceEntered  = "entered"
+)
+
+// Пояснение к номеру в текстовом отчете, пусто - номер из DMI
+func (s serialReport) sourceNote() string {
+	switch {
+	case s.Source == serialSourceOperator:
+		return "operator-provided"
+	case s.Source == serialSourceEntered:
+		return "entered, not verified"
+	case strings.HasPrefix(s.Source, serialSourceDMI+"-"):
+		return "from DMI " + strings.TrimPrefix(s.Source, serialSourceDMI+"-") + " serial"
+	}
+	return ""
+}
+
+// Часть имени файла: только безопасные символы
+func fileNamePart(serial string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, serial)
+	if safe == "" {
+		return "UNKNOWN"
 	}
+	return safe
 }
 
 type logCreatedMsg struct {
-	fileName string
+	fileName     string
+	jsonFileName string
 }
 
 // Анимация логотипа
@@ -921,111 +1423,13 @@ func getAnimatedLogo(state int) string {
 	return logos[state]
 }
 
-// Дополнительные флаги для видеотеста
-type testWaitingForInput struct{}
-type testPatternFinishMsg struct{}
-
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	// Общие сообщения обрабатываются здесь, остальные получает текущий этап
 	switch msg := msg.(type) {
-	case tea.KeyMsg:
-		// Если тест ожидает ввода пользователя, любая клавиша завершает тест
-		if m.videoTestActive && m.videoTestColor == 3 {
-			m.videoTestActive = false
-			m.state = stateAskVideoOk
-			m.showOverlay = true
-			return m, nil
-		}
-
-		switch msg.String() {
-		case "ctrl+c", "q":
-			return m, tea.Quit
-
-		case "enter":
-			switch m.state {
-			case stateShowInfo:
-				// Переходим к видео тесту
-				m.state = stateVideoTest
-				m.videoTestActive = true
-				m.videoTestColor = 0
-				m.videoTestStart = time.Now()
-				return m, startVideoTestCmd
-
-			case stateAskVideoOk:
-				// Если ответ "Y" (по умолчанию), продолжаем к проверке серийника
-				m.state = stateAskSerial
-				m.showOverlay = true
-				m.testPassed = true // Пользователь подтвердил успешное прохождение теста
-				return m, nil
-
-			case stateAskSerial:
-				// Проверяем серийный номер
-				m.userSerial = m.textInput.Value()
-				m.state = stateCheckSerial
-				return m, func() tea.Msg {
-					return checkSerialNumberCmd(m.userSerial, m.sysInfo.SerialNumber)
-				}
-
-			case stateSerialSuccess:
-				// Переходим к созданию логов после успешной проверки серийного номера
-				m.state = stateCreateLogs
-				m.showOverlay = true
-				return m, func() tea.Msg {
-					return createLogFilesCmd(m.sysInfo, m.dmidecodeRaw, m.testPassed, true)
-				}
-
-			case stateSerialError:
-				// Повторная проверка серийника
-				m.state = stateAskSerial
-				m.textInput.SetValue("")
-				return m, nil
-
-			case stateDone:
-				// Завершаем программу
-				return m, tea.Quit
-			}
-
-		case "n":
-			if m.state == stateAskVideoOk {
-				// Повторяем тест
-				m.state = stateVideoTest
-				m.videoTestActive = true
-				m.videoTestColor = 0
-				m.videoTestStart = time.Now()
-				m.testPassed = false // Тест не пройден
-				return m, startVideoTestCmd
-			}
-
-		case "r":
-			if m.state == stateDone || m.state == stateSerialError {
-				// Перезапуск системы
-				return m, func() tea.Msg {
-					exec.Command("reboot").Run()
-					return restartMsg{}
-				}
-			}
-
-		case "e":
-			if m.state == stateDone || m.state == stateSerialError {
-				// Выключение системы
-				return m, func() tea.Msg {
-					exec.Command("poweroff").Run()
-					return shutdownMsg{}
-				}
-			}
-
-		case "b":
-			// Возврат к экрану системной информации из определенных состояний
-			if m.state != stateInit && m.state != stateShowInfo && m.state != stateAskSerial {
-				m.state = stateShowInfo
-				m.showOverlay = false
-				m.videoTestActive = false
-				return m, nil
-			}
-		}
-
 	case tea.WindowSizeMsg:
+		// Размер получает и текущий этап: полноэкранные этапы раскладывают поле заново
 		m.width = msg.Width
 		m.height = msg.Height
 		m.viewport.Width = msg.Width
@@ -1035,74 +1439,37 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.err = msg.error
 		return m, tea.Quit
 
+	case operatorLoginMsg:
+		m.session = msg.session
+		m.collecting = true
+		return m, collectSystemInfoCmd(m.config)
+
 	case sysInfoCollectedMsg:
+		// Этап входа завершается, когда информация собрана
 		m.sysInfo = msg.sysInfo
 		m.dmidecodeRaw = msg.dmidecodeRaw
-		m.state = stateShowInfo
-		return m, nil
+		m.collecting = false
+		next, cmd := m.nextStage()
+		// Заказ показывается с самого начала, до сверки серийного номера
+		if path := m.config.Manifest.Path; path != "" && len(m.dmiManifestSerials()) > 0 {
+			cmd = tea.Batch(cmd, lookupManifestCmd(path, m.dmiManifestSerials(), false, 0))
+		}
+		return next, tea.Batch(cmd, sensorsTickCmd())
 
-	case startVideoTestMsg:
-		// Запускаем таймер для смены цветов в видеотесте
-		return m, tea.Tick(time.Second, func(time.Time) tea.Msg {
-			return videoTestTimerTickMsg{}
-		})
+	case manifestLookupMsg:
+		// Поиск по сверенному номеру обрабатывает этап serial
+		if !msg.verify {
+			m.manifest = msg.state(m.sysInfo)
+			return m, nil
+		}
 
-	case videoTestTimerTickMsg:
-		if m.videoTestActive {
-			elapsedSeconds := int(time.Since(m.videoTestStart).Seconds())
+	case pipelineRequestMsg:
+		return m.handleRequest(msg)
 
-			// Последняя фаза теста - SMPTE таблица - ожидает ввода пользователя
-			if m.videoTestColor == 3 {
-				// Продолжаем показывать тестовую таблицу, ожидая ввода
-				return m, tea.Tick(time.Second, func(time.Time) tea.Msg {
-					return videoTestTimerTickMsg{}
-				})
-			}
-
-			// Каждую секунду меняем цвет (красный -> зеленый -> синий -> тестовая таблица)
-			// Всего 3 секунды на цвета, потом тестовая таблица
-			if elapsedSeconds >= 3 {
-				// Переходим к тестовой таблице SMPTE и ждем ввода пользователя
-				m.videoTestColor = 3 // Устанавливаем последний тестовый паттерн
-				// Продолжаем таймер для обновления оставшегося времени
-				return m, tea.Tick(time.Second, func(time.Time) tea.Msg {
-					return videoTestTimerTickMsg{}
-				})
-			} else {
-				// Меняем цвет каждую секунду
-				m.videoTestColor = elapsedSeconds % 3 // Только первые три цвета
-				// Продолжаем таймер
-				return m, tea.Tick(time.Second, func(time.Time) tea.Msg {
-					return videoTestTimerTickMsg{}
-				})
-			}
-		}
-		return m, nil
-
-	case videoTestDoneMsg:
-		m.videoTestActive = false
-		m.state = stateAskVideoOk
-		return m, nil
-
-	case serialMatchedMsg:
-		// Серийный номер совпал, показываем сообщение об успехе
-		m.state = stateSerialSuccess
-		m.showOverlay = true
-		m.serialMatched = true
-		return m, updateLogoAnimationCmd
-
-	case serialMismatchMsg:
-		// Серийный номер не совпал, показываем ошибку
-		m.state = stateSerialError
-		m.userSerial = msg.entered
-		m.showOverlay = true
-		m.serialMatched = false
-		return m, updateLogoAnimationCmd
-
-	case logCreatedMsg:
-		m.state = stateDone
-		m.logFilePath = msg.fileName
-		return m, updateLogoAnimationCmd
+	case sensorsUpdateMsg:
+		// Живое обновление датчиков, в отчет попадает последний снимок
+		m.sysInfo.Sensors = msg.sensors
+		return m, sensorsTickCmd()
 
 	case logoAnimUpdateMsg:
 		m.logoAnimState = (m.logoAnimState + 1) % 4
@@ -1117,18 +1484,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		})
 	}
 
-	// Обновляем компоненты
-	switch m.state {
-	case stateInit:
+	// Пока собирается информация, работает только спиннер
+	if m.collecting {
+		if msg, ok := msg.(tea.KeyMsg); ok && (msg.String() == "ctrl+c" || msg.String() == "q") {
+			return m, tea.Quit
+		}
 		m.spinner, cmd = m.spinner.Update(msg)
 		return m, cmd
+	}
 
-	case stateAskSerial:
-		m.textInput, cmd = m.textInput.Update(msg)
-		return m, cmd
+	// Пока открыто окно заметки, клавиши получает оно
+	if msg, ok := msg.(tea.KeyMsg); ok && m.stageNote.active {
+		return m.updateStageNote(msg)
 	}
 
-	return m, nil
+	return m.updateStage(msg)
 }
 
 func (m model) View() string {
@@ -1141,23 +1511,12 @@ func (m model) View() string {
 		Width(m.width).
 		Align(lipgloss.Center)
 
-	// Уменьшаем внутренние отступы для основных контейнеров
 	borderStyle := lipgloss.NewStyle().
 		Border(lipgloss.NormalBorder()).
 		BorderForeground(lipgloss.Color("#3C3C3C")).
 		Padding(0, 0).
 		Width(m.width - 2)
 
-	// Изменяем стили секций для более точного контроля размеров
-	sectionStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#3C3C3C")).
-		Padding(0, 3)
-
-	sectionTitleStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#E8E8E8"))
-
 	errorStyle := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("#FFFFFF")).
@@ -1167,87 +1526,14 @@ func (m model) View() string {
 		Padding(1, 2).
 		Align(lipgloss.Center)
 
-	successStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FFFFFF")).
-		Background(lipgloss.Color("#00AA00")).
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#008800")).
-		Padding(1, 2).
-		Align(lipgloss.Center)
-
 	footerStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#CDCDCD")).
 		Padding(0, 1).
 		Width(m.width)
 
-	overlayStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#FFFFFF")).
-		Background(lipgloss.Color("#222222")).
-		Padding(2, 4).
-		Align(lipgloss.Center)
-
-	// Если активен видеотест, показываем его на весь экран
-	if m.videoTestActive {
-		var colorBg string
-		var colorName string
-		var progressInfo string
-
-		switch m.videoTestColor {
-		case 0:
-			colorBg = "#FF0000"
-			colorName = "RED"
-			progressInfo = fmt.Sprintf(
-				"Testing video... %s (1/4) [%d sec remaining]",
-				colorName,
-				3-int(time.Since(m.videoTestStart).Seconds()),
-			)
-		case 1:
-			colorBg = "#00FF00"
-			colorName = "GREEN"
-			progressInfo = fmt.Sprintf(
-				"Testing video... %s (2/4) [%d sec remaining]",
-				colorName,
-				3-int(time.Since(m.videoTestStart).Seconds()),
-			)
-		case 2:
-			colorBg = "#0000FF"
-			colorName = "BLUE"
-			progressInfo = fmt.Sprintf(
-				"Testing video... %s (3/4) [%d sec remaining]",
-				colorName,
-				3-int(time.Since(m.videoTestStart).Seconds()),
-			)
-		case 3:
-			// Настроечная таблица SMPTE HD на весь экран
-			return drawSMPTETestPattern(m.width, m.height)
-		}
-
-		// Создаем фон на весь экран с соответствующим цветом
-		testBg := lipgloss.NewStyle().
-			Background(lipgloss.Color(colorBg)).
-			Width(m.width).
-			Height(m.height - 1) // Используем весь экран, оставляя только одну строку для информации
-
-		return fmt.Sprintf(
-			"%s\n%s",
-			testBg.Render(""),
-			lipgloss.NewStyle().
-				Align(lipgloss.Center).
-				Width(m.width).
-				Foreground(lipgloss.Color("#FFFFFF")).
-				Background(lipgloss.Color("#000000")).
-				Render(progressInfo),
-		)
-	}
-
-	// Улучшенный расчет размеров для отображения
-	headerHeight := 1
-	footerHeight := 1
-	contentHeight := m.height - headerHeight - footerHeight - 2
+	contentHeight := m.height - 4
 
-	if m.state == stateInit {
+	if m.collecting {
 		spinnerContent := fmt.Sprintf(
 			"%s\n\n%s",
 			lipgloss.NewStyle().Align(lipgloss.Center).Width(m.width-2).Render("Collecting system information..."),
@@ -1256,7 +1542,7 @@ func (m model) View() string {
 
 		return lipgloss.JoinVertical(
 			lipgloss.Left,
-			titleStyle.Render("TROUBADOUR"),
+			titleStyle.Render(m.title()),
 			borderStyle.Copy().Height(contentHeight).Render(spinnerContent),
 		)
 	}
@@ -1271,11 +1557,55 @@ func (m model) View() string {
 
 		return lipgloss.JoinVertical(
 			lipgloss.Left,
-			titleStyle.Render("TROUBADOUR"),
+			titleStyle.Render(m.title()),
 			borderStyle.Copy().Height(contentHeight).Render(errorContent),
 		)
 	}
 
+	if m.stageNote.active {
+		return m.stageNoteView()
+	}
+	return m.currentStage().View(m)
+}
+
+// Экран системной информации
+func (m model) infoView() string {
+	// Стили для отображения
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FAFAFA")).
+		Background(lipgloss.Color("#1D1D1D")).
+		Padding(1, 0, 0, 0).
+		Width(m.width).
+		Align(lipgloss.Center)
+
+	// Уменьшаем внутренние отступы для основных контейнеров
+	borderStyle := lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("#3C3C3C")).
+		Padding(0, 0).
+		Width(m.width - 2)
+
+	// Изменяем стили секций для более точного контроля размеров
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3C3C3C")).
+		Padding(0, 3)
+
+	sectionTitleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#E8E8E8"))
+
+	footerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#CDCDCD")).
+		Padding(0, 1).
+		Width(m.width)
+
+	// Улучшенный расчет размеров для отображения
+	headerHeight := 1
+	footerHeight := 1
+	contentHeight := m.height - headerHeight - footerHeight - 2
+
 	// ИСПРАВЛЕННАЯ ВЕРСИЯ ОТОБРАЖЕНИЯ СИСТЕМНОЙ ИНФОРМАЦИИ
 
 	// Определяем, будем ли использовать две колонки
@@ -1348,6 +1678,82 @@ func (m model) View() string {
 			cpuContent.String(),
 		))
 
+	// ЗАКАЗ
+	orderSection := ""
+	if m.config.Manifest.Path != "" {
+		var orderContent string
+		manifest := m.unitManifest()
+		switch {
+		case manifest.found:
+			entry := manifest.entry
+			orderContent = fmt.Sprintf("Order: %s\nCustomer: %s", entry.Order, entry.Customer)
+			if entry.Model != "" {
+				orderContent += "\nModel: " + entry.Model
+			}
+			if manifest.problem != "" {
+				orderContent += "\n" + lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FF5555")).Render("✗ "+manifest.problem)
+			}
+		case manifest.problem != "":
+			orderContent = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FF5555")).Render("✗ " + manifest.problem)
+		default:
+			orderContent = "Looked up after serial number entry"
+		}
+
+		orderSection = sectionStyle.Copy().
+			Width(leftColumnWidth - 2).
+			Render(fmt.Sprintf("%s\n%s",
+				sectionTitleStyle.Render("─── ORDER ───"),
+				orderContent,
+			))
+	}
+
+	// КОМПЛЕКТАЦИЯ
+	bomSection := ""
+	if name, deviations, ok := m.bomCheck(); ok {
+		bomContent := lipgloss.NewStyle().Foreground(lipgloss.Color("#00AA00")).Render("Matches " + name)
+		if len(deviations) > 0 {
+			var lines []string
+			for _, deviation := range deviations {
+				lines = append(lines, "✗ "+deviation.String())
+			}
+			bomContent = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FF5555")).
+				Render(fmt.Sprintf("%s: %d deviations\n%s", name, len(deviations), strings.Join(lines, "\n")))
+		}
+
+		bomSection = sectionStyle.Copy().
+			Width(leftColumnWidth - 2).
+			Render(fmt.Sprintf("%s\n%s",
+				sectionTitleStyle.Render("─── BOM ───"),
+				bomContent,
+			))
+	}
+
+	// БАТАРЕЯ
+	batterySection := ""
+	if len(m.sysInfo.Power.Batteries) > 0 {
+		batteryContent := strings.Builder{}
+		for _, battery := range m.sysInfo.Power.Batteries {
+			batteryContent.WriteString(fmt.Sprintf("%s %s (%s)\n", battery.Manufacturer, battery.Model, battery.Technology))
+			batteryContent.WriteString(fmt.Sprintf("Capacity: %s\n", battery.capacityString()))
+
+			wear := fmt.Sprintf("Wear: %.1f%%, cycles: %d", battery.WearPercent, battery.CycleCount)
+			if battery.WearExceeded {
+				wear = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FF5555")).
+					Render(wear + " - EXCEEDS LIMIT")
+			}
+			batteryContent.WriteString(wear + "\n")
+			batteryContent.WriteString(fmt.Sprintf("Status: %s\n", battery.Status))
+		}
+		batteryContent.WriteString(fmt.Sprintf("AC adapter: %s", m.sysInfo.Power.acState()))
+
+		batterySection = sectionStyle.Copy().
+			Width(leftColumnWidth - 2).
+			Render(fmt.Sprintf("%s\n%s",
+				sectionTitleStyle.Render("─── BATTERY ───"),
+				batteryContent.String(),
+			))
+	}
+
 	// СЕТЬ
 	netContent := strings.Builder{}
 	for _, net := range m.sysInfo.Network {
@@ -1420,6 +1826,10 @@ func (m model) View() string {
 		gpuContent.WriteString(fmt.Sprintf("Resolution: %s\n", gpuRes))
 	}
 
+	if m.sysInfo.GPU.PanelResolution != "" {
+		gpuContent.WriteString(fmt.Sprintf("Panel: %s (%s)\n", m.sysInfo.GPU.PanelResolution, m.sysInfo.GPU.PanelConnector))
+	}
+
 	gpuSection := sectionStyle.Copy().
 		Width(rightColumnWidth - 2).
 		Render(fmt.Sprintf("%s\n%s",
@@ -1427,6 +1837,32 @@ func (m model) View() string {
 			gpuContent.String(),
 		))
 
+	// ЗВУК
+	audioContent := strings.Builder{}
+	for _, card := range m.sysInfo.Audio.Cards {
+		audioContent.WriteString(fmt.Sprintf("Card %s: %s (%s)\n", card.Index, card.Name, card.Driver))
+		for _, codec := range card.Codecs {
+			audioContent.WriteString(fmt.Sprintf("  Codec: %s\n", codec.Name))
+		}
+		for _, pcm := range card.PCMs {
+			audioContent.WriteString(fmt.Sprintf("  PCM %s: %s [%s]\n", pcm.Device, pcm.Name, pcm.directions()))
+		}
+	}
+	if len(m.sysInfo.Audio.Cards) == 0 {
+		audioContent.WriteString("No sound cards detected\n")
+	}
+	if len(m.sysInfo.Audio.MissingCodecs) > 0 {
+		audioContent.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FF5555")).
+			Render("Missing codec: "+strings.Join(m.sysInfo.Audio.MissingCodecs, ", ")) + "\n")
+	}
+
+	audioSection := sectionStyle.Copy().
+		Width(rightColumnWidth - 2).
+		Render(fmt.Sprintf("%s\n%s",
+			sectionTitleStyle.Render("─── AUDIO ───"),
+			strings.TrimSuffix(audioContent.String(), "\n"),
+		))
+
 	// ХРАНИЛИЩЕ (улучшенное отображение)
 	storageContent := strings.Builder{}
 	for i, storage := range m.sysInfo.Storage {
@@ -1444,6 +1880,24 @@ func (m model) View() string {
 				strings.TrimSpace(strings.ReplaceAll(storage.Model, "\n", " "))))
 		}
 
+		// Серийный номер и прошивка
+		if storage.Serial != "" {
+			storageContent.WriteString(fmt.Sprintf("S/N: %s\n", storage.Serial))
+		}
+		if storage.Firmware != "" {
+			storageContent.WriteString(fmt.Sprintf("FW: %s\n", storage.Firmware))
+		}
+
+		// Носитель, сектора и признак съемного устройства
+		media := "SSD"
+		if storage.Rotational {
+			media = "HDD"
+		}
+		if storage.Removable {
+			media += ", removable"
+		}
+		storageContent.WriteString(fmt.Sprintf("Media: %s, sector %s/%s\n", media, storage.LogicalSec, storage.PhysicalSec))
+
 		// Метка, если есть
 		if storage.Label != "" {
 			storageContent.WriteString(fmt.Sprintf("Label: %s\n", storage.Label))
@@ -1462,21 +1916,59 @@ func (m model) View() string {
 			storageContent.String(),
 		))
 
+	// ДАТЧИКИ (обновляются по таймеру)
+	sensorsContent := strings.Builder{}
+	for i, chip := range m.sysInfo.Sensors.Chips {
+		if i > 0 {
+			sensorsContent.WriteString("\n")
+		}
+		sensorsContent.WriteString(chip.Name + "\n")
+		for _, sensor := range chip.Sensors {
+			line := "  " + sensor.String()
+			if sensor.Critical {
+				line = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FF5555")).Render(line + " CRITICAL")
+			}
+			sensorsContent.WriteString(line + "\n")
+		}
+	}
+	if warning := m.sysInfo.Sensors.Warning; warning != "" {
+		sensorsContent.WriteString("Read error: " + warning + "\n")
+	} else if len(m.sysInfo.Sensors.Chips) == 0 {
+		sensorsContent.WriteString("No hwmon sensors found\n")
+	}
+
+	sensorsSection := sectionStyle.Copy().
+		Width(rightColumnWidth - 2).
+		Render(fmt.Sprintf("%s\n%s",
+			sectionTitleStyle.Render("─── SENSORS ───"),
+			strings.TrimSuffix(sensorsContent.String(), "\n"),
+		))
+
 	// Объединяем секции в колонки с точным позиционированием
 	var leftColumn, rightColumn string
 
-	leftColumn = lipgloss.JoinVertical(
-		lipgloss.Left,
-		logoSection,
-		procSection,
-		netSection,
-	)
+	leftSections := []string{logoSection}
+	if orderSection != "" {
+		leftSections = append(leftSections, orderSection)
+	}
+	if bomSection != "" {
+		leftSections = append(leftSections, bomSection)
+	}
+	leftSections = append(leftSections, procSection)
+	if batterySection != "" {
+		leftSections = append(leftSections, batterySection)
+	}
+	leftSections = append(leftSections, netSection)
+
+	leftColumn = lipgloss.JoinVertical(lipgloss.Left, leftSections...)
 
 	rightColumn = lipgloss.JoinVertical(
 		lipgloss.Left,
 		memSection,
 		gpuSection,
+		audioSection,
 		storageSection,
+		sensorsSection,
 	)
 
 	// Формируем полное отображение
@@ -1516,164 +2008,82 @@ func (m model) View() string {
 	}
 
 	// Создаем финальное отображение
-	footer := footerStyle.Render("Press ENTER to continue to video test...")
-	if m.state != stateInit && m.state != stateShowInfo {
-		footer = footerStyle.Render("Press ENTER to continue to video test... | Press B to return to system info")
+	next := m.resumeStage
+	if next == 0 {
+		next = min(m.stage+1, len(m.pipeline)-1)
 	}
+	footer := footerStyle.Render("Press ENTER to continue to " + m.pipeline[next].Name() + "...")
 
-	baseView := lipgloss.JoinVertical(
+	return lipgloss.JoinVertical(
 		lipgloss.Left,
-		titleStyle.Render("TROUBADOUR"),
+		titleStyle.Render(m.title()),
 		borderStyle.Copy().Height(contentHeight).Render(mainContent),
 		footer,
 	)
+}
 
-	// Если не нужно показывать оверлей, возвращаем базовый вид
-	if !m.showOverlay && m.state != stateVideoTest {
-		return baseView
-	}
-
-	// Подготавливаем оверлей в зависимости от состояния
-	var overlayContent string
-
-	switch m.state {
-	case stateAskVideoOk:
-		overlayContent = fmt.Sprintf(
-			"%s\n\n%s\n\n%s\n\n%s",
-			lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#00AAFF")).Render("Video Test Completed"),
-			"Did all test patterns display correctly?",
-			"[Y] Yes (default)   [n] No, run test again",
-			"[B] Return to system information",
-		)
-
-	case stateAskSerial:
-		overlayContent = fmt.Sprintf(
-			"%s\n\n%s\n\n%s",
-			lipgloss.NewStyle().Bold(true).Render("Serial Number Verification"),
-			fmt.Sprintf("System Serial Number: %s", m.sysInfo.SerialNumber),
-			fmt.Sprintf("Please enter Serial Number: %s", m.textInput.View()),
-		)
-
-	case stateSerialSuccess:
-		// Новый случай для успешной проверки серийного номера
-		successBox := successStyle.Width(45).Render(fmt.Sprintf(
-			"Serial numbers match!\n\nSystem: %s\nEntered: %s\n\nPress ENTER to continue",
-			m.sysInfo.SerialNumber, m.userSerial,
-		))
-
-		overlayContent = fmt.Sprintf(
-			"%s\n\n%s\n\n%s\n\n%s",
-			lipgloss.NewStyle().Bold(true).Render("Serial Number Verification Successful"),
-			lipgloss.NewStyle().Foreground(lipgloss.Color("#F5D76E")).Render(),
-			successBox,
-			"[B] Return to system information",
-		)
-
-	case stateSerialError:
-		errorBox := errorStyle.Width(45).Render(fmt.Sprintf(
-			"Serial numbers DO NOT match!\n\nSystem: %s\nEntered: %s\n\n[R] Restart system\n[E] Shutdown system\n[ENTER] Try again",
-			m.sysInfo.SerialNumber, m.userSerial,
-		))
-		overlayContent = fmt.Sprintf(
-			"%s\n\n%s\n\n%s\n\n%s",
-			lipgloss.NewStyle().Bold(true).Render("Serial Number Verification Failed"),
-			lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Render(),
-			errorBox,
-			"[B] Return to system information",
-		)
-
-	case stateCreateLogs:
-		// Анимация создания логов
-		progressChars := []string{"◐", "◓", "◑", "◒"}
-		progressText := []string{
-			"Hardware information collected",
-			"System verification completed",
-			"dmidecode data parsed",
-			"Writing log file...",
-		}
-
-		progressLines := make([]string, len(progressText))
-		for i, text := range progressText {
-			if i < m.progressAnimState {
-				progressLines[i] = "■ " + text
-			} else if i == m.progressAnimState {
-				progressLines[i] = progressChars[m.logoAnimState%len(progressChars)] + " " + text
-			} else {
-				progressLines[i] = "□ " + text
-			}
-		}
-
-		overlayContent = fmt.Sprintf(
-			"%s\n\n%s\n\n%s\n\n%s",
-			lipgloss.NewStyle().Bold(true).Render("Log Creation"),
-			"Creating system logs...",
-			strings.Join(progressLines, "\n"),
-			"[B] Return to system information",
-		)
-
-	case stateDone:
-		// Рассчитываем размер и положение оверлея
-		overlayWidth := m.width / 2
-		if overlayWidth < 50 {
-			overlayWidth = m.width - 10
+// Список проваленных проверок устройства по итогам всех этапов
+func (m model) failedChecks() []string {
+	var failed []string
+	for _, res := range m.stageResults() {
+		if res.ok() {
+			continue
 		}
-
-		// Создаем красивый вывод содержимого лога
-		logPreview := ""
-		logFile, err := os.ReadFile(m.logFilePath)
-
-		if err == nil {
-			logLines := strings.Split(string(logFile), "\n")
-			// Ограничиваем количество строк для предварительного просмотра
-			previewLines := 10
-			if len(logLines) > previewLines {
-				logLines = logLines[:previewLines]
-				logLines = append(logLines, "... (полный лог сохранен в файле)")
-			}
-
-			logPreview = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("#3C3C3C")).
-				Padding(1, 2).
-				Width(overlayWidth - 10).
-				Render(strings.Join(logLines, "\n"))
+		if len(res.Reasons) > 0 {
+			failed = append(failed, res.Reasons...)
 		} else {
-			logPreview = "Не удалось прочитать лог-файл: " + err.Error()
+			failed = append(failed, res.Stage+" "+string(res.Status))
 		}
+	}
+	return failed
+}
 
-		// Добавляем опции выключения/перезагрузки
-		options := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#EEEEEE")).
-			Render("[E] Выключить систему   [R] Перезагрузить систему   [ENTER] Выход")
+// Вердикты видеотеста, если этап есть в конвейере
+func (m model) videoResult() VideoTestResult {
+	v, _ := findStage[videoStage](m)
+	return v.result
+}
 
-		overlayContent = fmt.Sprintf(
-			"%s\n\n%s\n\n%s\n\n%s\n\n%s",
-			lipgloss.NewStyle().Bold(true).Render("Diagnostics Completed Successfully"),
-			lipgloss.NewStyle().Foreground(lipgloss.Color("#F5D76E")).Render(),
-			fmt.Sprintf("Output file: %s", m.logFilePath),
-			logPreview,
-			options,
-		)
+// Результаты чтения накопителей, если этап есть в конвейере
+func (m model) storageResults() []DiskReadResult {
+	s, _ := findStage[storageStage](m)
+	return s.results
+}
+
+// Результат теста клавиатуры, если он проводился
+func (m model) keyboardResult() *KeyboardTestResult {
+	kb, ok := findStage[keyboardStage](m)
+	if !ok || !kb.done {
+		return nil
 	}
+	return &kb.result
+}
 
-	// Рассчитываем размер и положение оверлея
-	overlayWidth := m.width / 2
-	if overlayWidth < 50 {
-		overlayWidth = m.width - 10
+// Результат теста указателя, если он проводился
+func (m model) pointerResult() *PointerTestResult {
+	pt, ok := findStage[pointerStage](m)
+	if !ok || !pt.done {
+		return nil
 	}
+	return &pt.result
+}
 
-	overlay := overlayStyle.Width(overlayWidth).Render(overlayContent)
+// Результат теста подсветки, если он проводился
+func (m model) backlightResult() *BacklightTestResult {
+	bt, ok := findStage[backlightStage](m)
+	if !ok || !bt.done {
+		return nil
+	}
+	return &bt.result
+}
 
-	// Создаем эффект наложения вручную
-	return lipgloss.PlaceHorizontal(
-		m.width,
-		lipgloss.Center,
-		lipgloss.PlaceVertical(
-			m.height,
-			lipgloss.Center,
-			overlay,
-		),
-	)
+// Результат теста камеры, если он проводился
+func (m model) cameraResult() *CameraTestResult {
+	ct, ok := findStage[cameraStage](m)
+	if !ok || !ct.done {
+		return nil
+	}
+	return &ct.result
 }
 
 // Функция для получения максимального значения
@@ -1685,17 +2095,44 @@ func max(a, b int) int {
 }
 
 func main() {
+	configPath := flag.String("config", defaultConfigPath, "путь к конфигурации станции")
+	hashPassword := flag.Bool("hash-supervisor-password", false, "прочитать пароль супервизора из stdin и вывести значение для serial.supervisor_password_hash")
+	flag.Parse()
+
+	// Хеш для конфигурации считается без root и без запуска интерфейса
+	if *hashPassword {
+		password, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		password = strings.TrimRight(password, "\r\n")
+		if password == "" {
+			fmt.Println("Пароль не задан")
+			os.Exit(1)
+		}
+		hash, err := hashSupervisorPassword(password)
+		if err != nil {
+			fmt.Println("Ошибка вычисления хеша:", err)
+			os.Exit(1)
+		}
+		fmt.Println(hash)
+		return
+	}
+
 	// Проверяем, что программа запущена от имени root
 	if os.Geteuid() != 0 {
 		fmt.Println("Эта программа должна быть запущена с правами root. Используйте sudo или su.")
 		os.Exit(1)
 	}
 
+	cfg, err := loadStationConfig(*configPath)
+	if err != nil {
+		fmt.Println("Ошибка загрузки конфигурации:", err)
+		os.Exit(1)
+	}
+
 	// Очищаем экран перед запуском для исключения артефактов отображения
 	fmt.Print("\033[H\033[2J")
 
 	p := tea.NewProgram(
-		initialModel(),
+		initialModel(cfg),
 		tea.WithAltScreen(),       // Используем альтернативный экран
 		tea.WithMouseCellMotion(), // Поддержка мыши для лучшего взаимодействия
 	)
@@ -1710,93 +2147,138 @@ func main() {
 	fmt.Print("\033[H\033[2J")
 }
 
-// Функция для отрисовки тестовой таблицы SMPTE HD на весь экран
-func drawSMPTETestPattern(width, height int) string {
-	var result strings.Builder
-
-	// Используем всю высоту экрана (без строки статуса внизу)
-	fullHeight := height - 1
-
-	// Рассчитываем высоту каждой полосы
-	// SMPTE HD тестовая таблица имеет 3 основные секции:
-	// - Верхние 7 цветных полос (75% яркости) - 40% высоты
-	// - Средние 7 цветных полос (100% яркости) - 30% высоты
-	// - Нижняя секция с различными тестовыми элементами - 30% высоты
+// Уровни SMPTE RP 219 в 8-битном RGB студийного диапазона (черный 16, белый 235),
+// иначе полосы PLUGE ниже и выше черного нельзя отличить от самого черного.
+const (
+	smpteGray40   = "#686868"
+	smpteGray15   = "#313131"
+	smpteBlack    = "#101010"
+	smpteNeg2     = "#0C0C0C"
+	smptePos2     = "#141414"
+	smptePos4     = "#191919"
+	smpteWhite100 = "#EBEBEB"
+	smpteNegI     = "#08426D"
+	smptePosQ     = "#4A1B7C"
+)
 
-	upperHeight := int(float64(fullHeight) * 0.4)          // 40% для верхних полос
-	middleHeight := int(float64(fullHeight) * 0.3)         // 30% для средних полос
-	lowerHeight := fullHeight - upperHeight - middleHeight // Оставшаяся часть для нижних элементов
+// Участок ряда таблицы: доля ширины и цвет (пустой цвет - яркостной клин)
+type smpteSegment struct {
+	width float64
+	color string
+}
 
-	// Создаем верхние 7 цветных полос (75% яркости)
-	colors75 := []string{"#C0C0C0", "#C0C000", "#00C0C0", "#00C000", "#C000C0", "#C00000", "#0000C0"}
-	colWidth := width / len(colors75)
+// Ряды таблицы SMPTE RP 219 для экрана 16:9. Ширина боковых полей d = 1/8,
+// ширина полосы c = 3/28, так что d + 7c + d = 1.
+var smpteRows = []struct {
+	height   float64
+	segments []smpteSegment
+}{
+	// Pattern 1: полосы 75% между полями 40% серого
+	{7.0 / 12, []smpteSegment{
+		{1.0 / 8, smpteGray40},
+		{3.0 / 28, "#B4B4B4"}, {3.0 / 28, "#B4B410"}, {3.0 / 28, "#10B4B4"}, {3.0 / 28, "#10B410"},
+		{3.0 / 28, "#B410B4"}, {3.0 / 28, "#B41010"}, {3.0 / 28, "#1010B4"},
+		{1.0 / 8, smpteGray40},
+	}},
+	// Pattern 2: 100% циан, -I, 75% белый, 100% синий
+	{1.0 / 12, []smpteSegment{
+		{1.0 / 8, "#10EBEB"},
+		{3.0 / 28, smpteNegI},
+		{6 * 3.0 / 28, "#B4B4B4"},
+		{1.0 / 8, "#1010EB"},
+	}},
+	// Pattern 3: 100% желтый, +Q, яркостной клин от черного до белого, 100% красный
+	{1.0 / 12, []smpteSegment{
+		{1.0 / 8, "#EBEB10"},
+		{3.0 / 28, smptePosQ},
+		{6 * 3.0 / 28, ""},
+		{1.0 / 8, "#EB1010"},
+	}},
+	// Pattern 4: 15% серый, черный, 100% белый, черный, PLUGE (-2%, 0, +2%, 0, +4%), черный
+	{3.0 / 12, []smpteSegment{
+		{1.0 / 8, smpteGray15},
+		{1.5 * 3.0 / 28, smpteBlack},
+		{2 * 3.0 / 28, smpteWhite100},
+		{5.0 / 6 * 3.0 / 28, smpteBlack},
+		{1.0 / 3 * 3.0 / 28, smpteNeg2},
+		{1.0 / 3 * 3.0 / 28, smpteBlack},
+		{1.0 / 3 * 3.0 / 28, smptePos2},
+		{1.0 / 3 * 3.0 / 28, smpteBlack},
+		{1.0 / 3 * 3.0 / 28, smptePos4},
+		{3.0 / 28, smpteBlack},
+		{1.0 / 8, smpteGray15},
+	}},
+}
 
-	// Отрисовываем верхние полосы
-	for row := 0; row < upperHeight; row++ {
-		for _, color := range colors75 {
-			result.WriteString(lipgloss.NewStyle().
-				Background(lipgloss.Color(color)).
-				Width(colWidth).
-				Render(""))
+// Разбиение размера на части по долям с округлением накопленной суммы,
+// чтобы части в сумме давали ровно весь размер без пустых колонок справа
+func splitByFractions(total int, fractions []float64) []int {
+	parts := make([]int, len(fractions))
+	sum, prev := 0.0, 0
+	for i, fraction := range fractions {
+		sum += fraction
+		edge := int(sum*float64(total) + 0.5)
+		if i == len(fractions)-1 {
+			edge = total
 		}
-		result.WriteString("\n")
+		parts[i] = max(edge-prev, 0)
+		prev += parts[i]
 	}
+	return parts
+}
 
-	// Отрисовываем средние полосы (100% яркости)
-	colors100 := []string{"#FFFFFF", "#FFFF00", "#00FFFF", "#00FF00", "#FF00FF", "#FF0000", "#0000FF"}
-	for row := 0; row < middleHeight; row++ {
-		for _, color := range colors100 {
-			result.WriteString(lipgloss.NewStyle().
-				Background(lipgloss.Color(color)).
-				Width(colWidth).
-				Render(""))
-		}
-		result.WriteString("\n")
+// Функция для отрисовки тестовой таблицы SMPTE HD на всю отведенную область
+func drawSMPTETestPattern(width, height int) string {
+	if width <= 0 || height <= 0 {
+		return ""
 	}
 
-	// Отрисовываем нижние тестовые элементы
-	lowerSection := [][]string{
-		{"#0000C0", "#000000", "#0000C0", "#000000", "#0000C0", "#000000", "#0000C0"},
-		{"#FFFFFF", "#000000", "#FFFFFF", "#000000", "#FFFFFF", "#000000", "#FFFFFF"},
+	heights := make([]float64, len(smpteRows))
+	for i, row := range smpteRows {
+		heights[i] = row.height
 	}
+	rowHeights := splitByFractions(height, heights)
 
-	// Разделяем нижнюю секцию на равные части для каждого типа паттерна
-	patternHeight := lowerHeight / len(lowerSection)
-	if patternHeight < 1 {
-		patternHeight = 1
+	// Один стиль на цвет вместо нового стиля на каждую клетку
+	styles := make(map[string]lipgloss.Style)
+	paint := func(color, text string) string {
+		style, ok := styles[color]
+		if !ok {
+			style = lipgloss.NewStyle().Background(lipgloss.Color(color))
+			styles[color] = style
+		}
+		return style.Render(text)
 	}
 
-	for i, pattern := range lowerSection {
-		// Определяем сколько строк отрисовать для текущего паттерна
-		rowsToDraw := patternHeight
-		if i == len(lowerSection)-1 {
-			// Последний паттерн получает все оставшиеся строки
-			rowsToDraw = lowerHeight - (patternHeight * i)
+	lines := make([]string, 0, height)
+	for i, row := range smpteRows {
+		fractions := make([]float64, len(row.segments))
+		for j, segment := range row.segments {
+			fractions[j] = segment.width
 		}
+		widths := splitByFractions(width, fractions)
 
-		for row := 0; row < rowsToDraw; row++ {
-			for _, color := range pattern {
-				result.WriteString(lipgloss.NewStyle().
-					Background(lipgloss.Color(color)).
-					Width(colWidth).
-					Render(""))
+		var line strings.Builder
+		for j, segment := range row.segments {
+			if segment.color != "" {
+				line.WriteString(paint(segment.color, strings.Repeat(" ", widths[j])))
+				continue
+			}
+
+			// Яркостной клин: от 16 до 235 по ширине участка
+			for x := 0; x < widths[j]; x++ {
+				level := 16
+				if widths[j] > 1 {
+					level += x * (235 - 16) / (widths[j] - 1)
+				}
+				line.WriteString(paint(fmt.Sprintf("#%02X%02X%02X", level, level, level), " "))
 			}
-			result.WriteString("\n")
 		}
-	}
 
-	// Добавляем информацию о необходимости нажать клавишу для продолжения (в нижней строке экрана)
-	progressInfo := "Press any key to continue... | B to return to system info"
+		for y := 0; y < rowHeights[i]; y++ {
+			lines = append(lines, line.String())
+		}
+	}
 
-	return fmt.Sprintf(
-		"%s%s",
-		result.String(),
-		lipgloss.NewStyle().
-			Align(lipgloss.Center).
-			Width(width).
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Background(lipgloss.Color("#000000")).
-			Bold(true).
-			Render(progressInfo),
-	)
+	return strings.Join(lines, "\n")
 }
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Колонки манифеста. serial и work_order обязательны, result, tested_at и
+// report добавляются при первой записи результата.
+const (
+	manifestSerial   = "serial"
+	manifestOrder    = "work_order"
+	manifestCustomer = "customer"
+	manifestModel    = "model"
+	manifestStatus   = "status"
+	manifestResult   = "result"
+	manifestTestedAt = "tested_at"
+	manifestReport   = "report"
+)
+
+// Статус строки, после которого устройство не принимается на проверку
+const manifestShippedStatus = "shipped"
+
+// Строка манифеста: заказ, под который собрано устройство
+type ManifestEntry struct {
+	Serial   string `json:"serial"`
+	Order    string `json:"work_order"`
+	Customer string `json:"customer,omitempty"`
+	Model    string `json:"model,omitempty"` // Подстрока ожидаемого Product Name
+	Status   string `json:"status,omitempty"`
+}
+
+// Состояние поиска устройства в манифесте
+type manifestState struct {
+	entry    ManifestEntry
+	found    bool
+	problem  string // Почему устройство не принимается, пусто - принимается
+	verified bool   // Найдено по сверенному номеру и принято
+}
+
+// Результат поиска в манифесте. verify - поиск по сверенному номеру на этапе
+// serial, иначе предварительный поиск по DMI для экрана информации.
+// seq - номер поиска этапа serial, по нему отбрасываются устаревшие ответы.
+type manifestLookupMsg struct {
+	entry  ManifestEntry
+	err    error
+	verify bool
+	seq    int
+}
+
+// Результат записи итога проверки в манифест
+type manifestUpdatedMsg struct {
+	err error
+}
+
+// Чтение манифеста: строки без заголовка и номера колонок по имени
+func readManifest(path string) ([][]string, map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка разбора %s: %v", path, err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%s: нет заголовка", path)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{manifestSerial, manifestOrder} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("%s: нет колонки %s", path, required)
+		}
+	}
+	return records, columns, nil
+}
+
+// Значение колонки строки, пусто, если колонки нет
+func manifestField(row []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// Номер строки манифеста с одним из серийных номеров
+func findManifestRow(records [][]string, columns map[string]int, serials []string) int {
+	for i, row := range records[1:] {
+		serial := normalizeSerial(manifestField(row, columns, manifestSerial))
+		if serial == "" {
+			continue
+		}
+		for _, candidate := range serials {
+			if normalizeSerial(candidate) == serial {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// Поиск устройства в манифесте по первому найденному серийному номеру
+func lookupManifest(path string, serials []string) (ManifestEntry, error) {
+	records, columns, err := readManifest(path)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	i := findManifestRow(records, columns, serials)
+	if i < 0 {
+		return ManifestEntry{}, fmt.Errorf("unit is not in the manifest")
+	}
+	row := records[i]
+	return ManifestEntry{
+		Serial:   manifestField(row, columns, manifestSerial),
+		Order:    manifestField(row, columns, manifestOrder),
+		Customer: manifestField(row, columns, manifestCustomer),
+		Model:    manifestField(row, columns, manifestModel),
+		Status:   manifestField(row, columns, manifestStatus),
+	}, nil
+}
+
+// Причина, по которой устройство из манифеста не принимается на проверку
+func (e ManifestEntry) problem(info SystemInfo) string {
+	if strings.EqualFold(e.Status, manifestShippedStatus) {
+		return fmt.Sprintf("unit already shipped (order %s)", e.Order)
+	}
+	if e.Model != "" && !containsFold(info.ProductName, e.Model) {
+		return fmt.Sprintf("order %s expects model %s, got %s", e.Order, e.Model, info.ProductName)
+	}
+	return ""
+}
+
+func lookupManifestCmd(path string, serials []string, verify bool, seq int) tea.Cmd {
+	return func() tea.Msg {
+		entry, err := lookupManifest(path, serials)
+		return manifestLookupMsg{entry: entry, err: err, verify: verify, seq: seq}
+	}
+}
+
+// Серийные номера из DMI для предварительного поиска, без заглушек
+func (m model) dmiManifestSerials() []string {
+	var serials []string
+	for _, field := range m.config.Serial.Fields {
+		if value := m.sysInfo.dmiSerial(field); !isPlaceholderSerial(value, m.config.Serial.PlaceholderSerials) {
+			serials = append(serials, value)
+		}
+	}
+	return serials
+}
+
+// Итог поиска в манифесте
+func (msg manifestLookupMsg) state(info SystemInfo) manifestState {
+	if msg.err != nil {
+		return manifestState{problem: msg.err.Error()}
+	}
+	state := manifestState{entry: msg.entry, found: true, problem: msg.entry.problem(info)}
+	state.verified = msg.verify && state.problem == ""
+	return state
+}
+
+// Заказ устройства: после поиска по сверенному номеру - его итог, до этого -
+// предварительный поиск по DMI
+func (m model) unitManifest() manifestState {
+	if serial, ok := findStage[serialStage](m); ok && serial.manifestChecked {
+		return serial.manifest
+	}
+	return m.manifest
+}
+
+// Сколько ждать, пока другая станция допишет общий манифест
+const manifestLockTimeout = 10 * time.Second
+
+// Исключительная блокировка манифеста на время чтения и замены. Блокируется
+// отдельный файл рядом: сам манифест подменяется через rename, и блокировка
+// на нем потерялась бы вместе со старым inode.
+func lockManifest(path string) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(manifestLockTimeout)
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if err != syscall.EWOULDBLOCK && err != syscall.EINTR {
+			f.Close()
+			return nil, fmt.Errorf("lock manifest: %v", err)
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("manifest is locked by another station for over %s", manifestLockTimeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// Запись итога проверки в строку устройства. Файл заменяется целиком через
+// временный файл, чтобы при выключении не остался наполовину записанный манифест.
+// Станции с общим манифестом записывают по очереди, иначе одна затерла бы
+// результат другой.
+func updateManifestCmd(path, serial, verdict, report string) tea.Cmd {
+	return func() tea.Msg {
+		unlock, err := lockManifest(path)
+		if err != nil {
+			return manifestUpdatedMsg{err}
+		}
+		defer unlock()
+
+		records, columns, err := readManifest(path)
+		if err != nil {
+			return manifestUpdatedMsg{err}
+		}
+		i := findManifestRow(records, columns, []string{serial})
+		if i < 0 {
+			return manifestUpdatedMsg{fmt.Errorf("unit %s is no longer in the manifest", serial)}
+		}
+
+		values := map[string]string{
+			manifestResult:   verdict,
+			manifestTestedAt: time.Now().Format(time.RFC3339),
+			manifestReport:   report,
+		}
+		for _, name := range []string{manifestResult, manifestTestedAt, manifestReport} {
+			col, ok := columns[name]
+			if !ok {
+				col = len(records[0])
+				columns[name] = col
+				records[0] = append(records[0], name)
+			}
+			for len(records[i]) <= col {
+				records[i] = append(records[i], "")
+			}
+			records[i][col] = values[name]
+		}
+
+		tmp, err := os.CreateTemp(filepath.Dir(path), ".manifest-*.csv")
+		if err != nil {
+			return manifestUpdatedMsg{err}
+		}
+		defer os.Remove(tmp.Name())
+
+		w := csv.NewWriter(tmp)
+		w.WriteAll(records)
+		if err := w.Error(); err != nil {
+			tmp.Close()
+			return manifestUpdatedMsg{err}
+		}
+		if err := tmp.Close(); err != nil {
+			return manifestUpdatedMsg{err}
+		}
+		if info, err := os.Stat(path); err == nil {
+			os.Chmod(tmp.Name(), info.Mode())
+		}
+		return manifestUpdatedMsg{os.Rename(tmp.Name(), path)}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestUpdateManifestConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.csv")
+	rows := []string{"serial,work_order"}
+	for i := range 20 {
+		rows = append(rows, fmt.Sprintf("SN%02d,WO-%d", i, i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(rows, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Станции записывают результаты одновременно, ни один не должен потеряться
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg := updateManifestCmd(path, fmt.Sprintf("SN%02d", i), "PASS", fmt.Sprintf("report%02d.json", i))()
+			if err := msg.(manifestUpdatedMsg).err; err != nil {
+				t.Errorf("update SN%02d: %v", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	records, columns, err := readManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 20 {
+		row := findManifestRow(records, columns, []string{fmt.Sprintf("SN%02d", i)})
+		if row < 0 {
+			t.Fatalf("SN%02d is missing from the manifest", i)
+		}
+		if records[row][columns[manifestResult]] != "PASS" {
+			t.Errorf("SN%02d lost its result: %q", i, records[row])
+		}
+	}
+}
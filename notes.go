@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Повод для заметки оператора
+type noteKind string
+
+const (
+	noteFail    noteKind = "fail"    // Этап провален или завершился ошибкой
+	noteRetry   noteKind = "retry"   // Этап или проверка запускаются повторно
+	noteGeneral noteKind = "general" // Общая заметка перед записью отчета
+)
+
+// Код дефекта из конфигурации станции
+type DefectCode struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// Заметка оператора к этапу
+type StageNote struct {
+	Kind        noteKind  `json:"kind"`
+	Code        string    `json:"code,omitempty"`
+	Description string    `json:"description,omitempty"` // Описание кода дефекта
+	Text        string    `json:"text,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// Строка заметки для текстового отчета
+func (n StageNote) String() string {
+	var parts []string
+	if n.Code != "" {
+		parts = append(parts, fmt.Sprintf("[%s %s]", n.Code, n.Description))
+	}
+	if n.Text != "" {
+		parts = append(parts, n.Text)
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, " "), n.Kind)
+}
+
+// Окно ввода заметки. После сохранения или пропуска выполняется then.
+type stageNoteState struct {
+	active bool
+	kind   noteKind
+	stage  int      // Этап, к которому относится заметка
+	codes  []string // Варианты кодов, пустая строка - без кода
+	cursor int
+	input  textinput.Model
+	then   func(model) (tea.Model, tea.Cmd)
+}
+
+func newNoteInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "Заметка (необязательно)"
+	ti.CharLimit = 200
+	ti.Width = 50
+	return ti
+}
+
+// Запрос заметки оператора перед продолжением
+func (m model) askStageNote(kind noteKind, then func(model) (tea.Model, tea.Cmd)) (tea.Model, tea.Cmd) {
+	note := stageNoteState{
+		active: true,
+		kind:   kind,
+		stage:  m.stage,
+		input:  newNoteInput(),
+		then:   then,
+	}
+
+	// При провале код дефекта обязателен, если коды заданы в конфигурации
+	if kind != noteFail || len(m.config.DefectCodes) == 0 {
+		note.codes = append(note.codes, "")
+	}
+	if kind != noteGeneral {
+		for _, defect := range m.config.DefectCodes {
+			note.codes = append(note.codes, defect.Code)
+		}
+	}
+	if kind == noteGeneral {
+		note.input.SetValue(m.generalNote)
+	}
+
+	m.stageNote = note
+	return m, m.stageNote.input.Focus()
+}
+
+// Описание кода дефекта
+func (m model) defectDescription(code string) string {
+	for _, defect := range m.config.DefectCodes {
+		if defect.Code == code {
+			return defect.Description
+		}
+	}
+	return ""
+}
+
+// Клавиши окна заметки
+func (m model) updateStageNote(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	note := &m.stageNote
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "up":
+		if note.cursor > 0 {
+			note.cursor--
+		}
+		return m, nil
+
+	case "down":
+		if note.cursor < len(note.codes)-1 {
+			note.cursor++
+		}
+		return m, nil
+
+	case "esc":
+		// Пропустить можно только заметку, в которой не требуется код
+		if note.kind == noteFail && note.codes[0] != "" {
+			return m, nil
+		}
+		then := note.then
+		m.stageNote = stageNoteState{}
+		return then(m)
+
+	case "enter":
+		text := strings.TrimSpace(note.input.Value())
+		code := note.codes[note.cursor]
+		if note.kind == noteGeneral {
+			m.generalNote = text
+		} else if text != "" || code != "" {
+			notes := slices.Clone(m.stageNotes)
+			notes[note.stage] = append(slices.Clone(notes[note.stage]), StageNote{
+				Kind:        note.kind,
+				Code:        code,
+				Description: m.defectDescription(code),
+				Text:        text,
+				Time:        time.Now(),
+			})
+			m.stageNotes = notes
+		}
+		then := note.then
+		m.stageNote = stageNoteState{}
+		return then(m)
+	}
+
+	var cmd tea.Cmd
+	note.input, cmd = note.input.Update(msg)
+	return m, cmd
+}
+
+// Окно ввода заметки
+func (m model) stageNoteView() string {
+	note := m.stageNote
+	stageName := m.pipeline[note.stage].Name()
+
+	var title string
+	switch note.kind {
+	case noteFail:
+		title = fmt.Sprintf("Stage %s failed: what is wrong?", stageName)
+	case noteRetry:
+		title = fmt.Sprintf("Retrying %s: why?", stageName)
+	default:
+		title = "General note for the report"
+	}
+
+	var codes []string
+	for i, code := range note.codes {
+		line := "  " + code + " " + m.defectDescription(code)
+		if code == "" {
+			line = "  (no defect code)"
+		}
+		if i == note.cursor {
+			line = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#F5D76E")).Render("▸" + line[1:])
+		}
+		codes = append(codes, line)
+	}
+
+	help := "[↑/↓] Defect code   [ENTER] Save   [ESC] Skip"
+	if note.kind == noteFail && note.codes[0] != "" {
+		help = "[↑/↓] Defect code   [ENTER] Save"
+	}
+	if len(note.codes) <= 1 {
+		help = "[ENTER] Save   [ESC] Skip"
+	}
+
+	content := lipgloss.NewStyle().Bold(true).Render(title) + "\n\n"
+	if len(note.codes) > 1 {
+		content += lipgloss.NewStyle().Align(lipgloss.Left).Render(strings.Join(codes, "\n")) + "\n\n"
+	}
+	return m.overlayView(content + note.input.View() + "\n\n" + help)
+}
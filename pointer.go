@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Действие, которое оператор должен выполнить мышью или тачпадом
+type PointerAction struct {
+	Name   string
+	Done   bool
+	DoneAt time.Time
+}
+
+// Результат теста указателя
+type PointerTestResult struct {
+	Actions      []PointerAction
+	PathCoverage float64 // Доля пройденного контура, %
+	Failed       bool    // Оператор отметил тест как проваленный
+	Passed       bool
+}
+
+// Мишень для щелчка на краю экрана
+type pointerTarget struct {
+	name string
+	x, y int
+}
+
+// Этап проверки тачпада и мыши
+type pointerStage struct {
+	width, height int
+	targets       []pointerTarget
+	path          map[[2]int]bool // Клетки контура для обводки
+	traced        map[[2]int]bool // Пройденные клетки контура
+	lastDrag      *[2]int         // Предыдущая точка перетаскивания
+	actions       map[string]time.Time
+	minCoverage   float64
+	result        PointerTestResult
+	done          bool
+}
+
+// Порядок действий в отчете
+var pointerActionOrder = []string{
+	"click top-left", "click top", "click top-right", "click right",
+	"click bottom-right", "click bottom", "click bottom-left", "click left",
+	"left button", "right button", "middle button",
+	"scroll up", "scroll down", "trace path",
+}
+
+// Допуск попадания в мишень, клеток
+const pointerTargetTolerance = 1
+
+func newPointerTest(cfg PointerTestConfig, width, height int) pointerStage {
+	state := pointerStage{
+		actions:     make(map[string]time.Time),
+		minCoverage: cfg.MinPathCoverage,
+	}
+	state.layout(width, height)
+	if state.minCoverage <= 0 {
+		state.mark("trace path")
+	}
+	return state
+}
+
+// Мишени и контур под размер экрана. Выполненные действия сохраняются, а
+// обводку после изменения размера нужно пройти заново по новому контуру.
+func (p *pointerStage) layout(width, height int) {
+	// Последняя строка экрана отведена под строку состояния
+	h := height - 1
+	right, bottom := width-2, h-1
+	midX, midY := width/2, h/2
+
+	p.width, p.height = width, height
+	p.targets = []pointerTarget{
+		{"click top-left", 1, 0}, {"click top", midX, 0}, {"click top-right", right, 0},
+		{"click right", right, midY}, {"click bottom-right", right, bottom},
+		{"click bottom", midX, bottom}, {"click bottom-left", 1, bottom}, {"click left", 1, midY},
+	}
+	p.path = make(map[[2]int]bool)
+	p.traced = make(map[[2]int]bool)
+	p.lastDrag = nil
+
+	// Контур для обводки: прямоугольник в центре экрана
+	x0, y0, x1, y1 := width/4, h/4, width*3/4, h*3/4
+	for x := x0; x <= x1; x++ {
+		p.path[[2]int{x, y0}] = true
+		p.path[[2]int{x, y1}] = true
+	}
+	for y := y0; y <= y1; y++ {
+		p.path[[2]int{x0, y}] = true
+		p.path[[2]int{x1, y}] = true
+	}
+}
+
+func (p *pointerStage) mark(action string) {
+	if _, ok := p.actions[action]; !ok {
+		p.actions[action] = time.Now()
+	}
+}
+
+// Учет точки перетаскивания с интерполяцией пропущенных клеток
+func (p *pointerStage) trace(x, y int) {
+	from := [2]int{x, y}
+	if p.lastDrag != nil {
+		from = *p.lastDrag
+	}
+
+	steps := max(abs(x-from[0]), abs(y-from[1]))
+	for i := 0; i <= steps; i++ {
+		cx, cy := x, y
+		if steps > 0 {
+			cx = from[0] + (x-from[0])*i/steps
+			cy = from[1] + (y-from[1])*i/steps
+		}
+		// Касание соседней клетки тоже засчитывается
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				cell := [2]int{cx + dx, cy + dy}
+				if p.path[cell] {
+					p.traced[cell] = true
+				}
+			}
+		}
+	}
+
+	p.lastDrag = &[2]int{x, y}
+	if p.coverage() >= p.minCoverage {
+		p.mark("trace path")
+	}
+}
+
+// Доля пройденного контура, %
+func (p pointerStage) coverage() float64 {
+	if len(p.path) == 0 {
+		return 0
+	}
+	return float64(len(p.traced)) * 100 / float64(len(p.path))
+}
+
+func (p *pointerStage) handleMouse(msg tea.MouseMsg) {
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		p.mark("scroll up")
+	case msg.Button == tea.MouseButtonWheelDown:
+		p.mark("scroll down")
+	case msg.Action == tea.MouseActionPress:
+		switch msg.Button {
+		case tea.MouseButtonLeft:
+			p.mark("left button")
+			for _, target := range p.targets {
+				if abs(msg.X-target.x) <= pointerTargetTolerance && abs(msg.Y-target.y) <= pointerTargetTolerance {
+					p.mark(target.name)
+				}
+			}
+			p.lastDrag = nil
+			p.trace(msg.X, msg.Y)
+		case tea.MouseButtonRight:
+			p.mark("right button")
+		case tea.MouseButtonMiddle:
+			p.mark("middle button")
+		}
+	case msg.Action == tea.MouseActionMotion && msg.Button == tea.MouseButtonLeft:
+		// При WithMouseCellMotion движение приходит только с зажатой кнопкой
+		p.trace(msg.X, msg.Y)
+	case msg.Action == tea.MouseActionRelease:
+		p.lastDrag = nil
+	}
+}
+
+// Итог теста по текущему состоянию
+func (p pointerStage) evaluate(failed bool) PointerTestResult {
+	res := PointerTestResult{PathCoverage: p.coverage(), Failed: failed, Passed: !failed}
+	for _, name := range pointerActionOrder {
+		at, ok := p.actions[name]
+		res.Actions = append(res.Actions, PointerAction{Name: name, Done: ok, DoneAt: at})
+		if !ok {
+			res.Passed = false
+		}
+	}
+	return res
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func (pointerStage) Name() string { return "pointer" }
+
+func (pointerStage) Init(m model) (Stage, tea.Cmd) {
+	return newPointerTest(m.config.Pointer, m.width, m.height), nil
+}
+
+func (p pointerStage) Update(m model, msg tea.Msg) (Stage, tea.Cmd) {
+	if p.done {
+		return p, nil
+	}
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// Мишени по старым координатам оказались бы за краем экрана
+		p.layout(msg.Width, msg.Height)
+
+	case tea.MouseMsg:
+		p.handleMouse(msg)
+		if res := p.evaluate(false); res.Passed {
+			p.result = res
+			p.done = true
+			return p, m.request(requestNext)
+		}
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return p, tea.Quit
+		case "f":
+			// Оператор отмечает тест как проваленный
+			p.result = p.evaluate(true)
+			p.done = true
+			return p, m.request(requestNext)
+		case "b":
+			return p, m.request(requestBack)
+		}
+	}
+	return p, nil
+}
+
+// Отрисовка поля теста указателя на весь экран
+func (p pointerStage) View(m model) string {
+	h := p.height - 1
+	if h < 1 || p.width < 1 {
+		return ""
+	}
+
+	const (
+		colorEmpty  = ""
+		colorTodo   = "#FF5555"
+		colorDone   = "#00AA00"
+		colorPath   = "#3C6EB4"
+		colorTraced = "#00AA00"
+	)
+
+	// Холст: символ и цвет фона каждой клетки
+	type cell struct {
+		ch    rune
+		color string
+	}
+	canvas := make([][]cell, h)
+	for y := range canvas {
+		canvas[y] = make([]cell, p.width)
+		for x := range canvas[y] {
+			canvas[y][x] = cell{' ', colorEmpty}
+		}
+	}
+	set := func(x, y int, ch rune, color string) {
+		if y >= 0 && y < h && x >= 0 && x < p.width {
+			canvas[y][x] = cell{ch, color}
+		}
+	}
+
+	for point := range p.path {
+		color := colorPath
+		if p.traced[point] {
+			color = colorTraced
+		}
+		set(point[0], point[1], ' ', color)
+	}
+
+	for _, target := range p.targets {
+		color := colorTodo
+		if _, ok := p.actions[target.name]; ok {
+			color = colorDone
+		}
+		for dx := -1; dx <= 1; dx++ {
+			set(target.x+dx, target.y, '+', color)
+		}
+	}
+
+	// Склеиваем соседние клетки одного цвета в один отрезок
+	var out strings.Builder
+	for _, row := range canvas {
+		start := 0
+		for x := 1; x <= len(row); x++ {
+			if x < len(row) && row[x].color == row[start].color {
+				continue
+			}
+			var segment strings.Builder
+			for _, c := range row[start:x] {
+				segment.WriteRune(c.ch)
+			}
+			if color := row[start].color; color != colorEmpty {
+				out.WriteString(lipgloss.NewStyle().Background(lipgloss.Color(color)).Render(segment.String()))
+			} else {
+				out.WriteString(segment.String())
+			}
+			start = x
+		}
+		out.WriteString("\n")
+	}
+
+	// Краткий перечень выполненных действий в строке состояния
+	res := p.evaluate(false)
+	var done []string
+	targets := 0
+	for _, action := range res.Actions {
+		if !action.Done {
+			continue
+		}
+		if strings.HasPrefix(action.Name, "click ") {
+			targets++
+		} else {
+			done = append(done, action.Name)
+		}
+	}
+	status := fmt.Sprintf("Targets %d/%d | path %.0f%% | %s | [F] Mark as failed",
+		targets, len(p.targets), res.PathCoverage, strings.Join(done, ", "))
+	return out.String() + lipgloss.NewStyle().
+		Align(lipgloss.Center).
+		Width(p.width).
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#000000")).
+		Bold(true).
+		Render(status)
+}
+
+func (p pointerStage) Result(m model) StageResult {
+	var res StageResult
+	if !p.done {
+		return res
+	}
+
+	pt := p.result
+	var missing []string
+	for _, action := range pt.Actions {
+		if !action.Done {
+			missing = append(missing, action.Name)
+		}
+	}
+	res.value("actions done", "%d of %d", len(pt.Actions)-len(missing), len(pt.Actions))
+	res.value("path coverage", "%.0f%%", pt.PathCoverage)
+	if len(missing) > 0 {
+		res.fail("pointer: not done: %s", strings.Join(missing, ", "))
+	}
+	if pt.Failed {
+		res.fail("pointer: marked failed by operator")
+	}
+	res.settle()
+	return res
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPointerResize(t *testing.T) {
+	m := model{width: 120, height: 40, config: StationConfig{Pointer: PointerTestConfig{MinPathCoverage: 90}}}
+	stage, _ := pointerStage{}.Init(m)
+	p := stage.(pointerStage)
+	p.handleMouse(tea.MouseMsg{X: 1, Y: 0, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+
+	// После уменьшения окна все мишени остаются на экране
+	stage, _ = p.Update(m, tea.WindowSizeMsg{Width: 80, Height: 24})
+	p = stage.(pointerStage)
+	for _, target := range p.targets {
+		if target.x < 0 || target.x >= 80 || target.y < 0 || target.y >= 23 {
+			t.Errorf("target %q at %d,%d is outside 80x24", target.name, target.x, target.y)
+		}
+	}
+	if _, ok := p.actions["click top-left"]; !ok {
+		t.Error("resize dropped a completed click")
+	}
+
+	// Щелчок по новой мишени засчитывается
+	p.handleMouse(tea.MouseMsg{X: 78, Y: 22, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+	if _, ok := p.actions["click bottom-right"]; !ok {
+		t.Error("click on the moved bottom-right target was not counted")
+	}
+}
+
+func TestPointerNoPathCoverage(t *testing.T) {
+	p := newPointerTest(PointerTestConfig{MinPathCoverage: 0}, 80, 24)
+	if _, ok := p.actions["trace path"]; !ok {
+		t.Error("min_path_coverage 0 still requires tracing the path")
+	}
+
+	cfg := defaultStationConfig()
+	cfg.Pointer.MinPathCoverage = 0
+	if err := cfg.validate(); err != nil {
+		t.Errorf("validate() with min_path_coverage 0: %v", err)
+	}
+}
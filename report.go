@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Завершающий этап: запись отчета и итоговый экран
+type reportStage struct {
+	logFilePath       string
+	reportJSONPath    string // Путь к JSON-отчету
+	manifestUpdated   bool   // Результат записан в манифест
+	manifestUpdateErr string
+}
+
+func (reportStage) Name() string { return "report" }
+
+func (reportStage) Init(m model) (Stage, tea.Cmd) {
+	report := m.unitReport(time.Now())
+	return reportStage{}, func() tea.Msg {
+		return createLogFilesCmd(report)
+	}
+}
+
+func (r reportStage) Update(m model, msg tea.Msg) (Stage, tea.Cmd) {
+	switch msg := msg.(type) {
+	case logCreatedMsg:
+		r.logFilePath = msg.fileName
+		r.reportJSONPath = msg.jsonFileName
+
+		// Итог проверки записывается в строку устройства в манифесте
+		if manifest := m.unitManifest(); manifest.verified {
+			verdict := "PASS"
+			if !unitPassed(m.stageResults()) {
+				verdict = "FAIL"
+			}
+			return r, tea.Batch(updateLogoAnimationCmd,
+				updateManifestCmd(m.config.Manifest.Path, manifest.entry.Serial, verdict, msg.fileName))
+		}
+		return r, updateLogoAnimationCmd
+
+	case manifestUpdatedMsg:
+		r.manifestUpdated = msg.err == nil
+		if msg.err != nil {
+			r.manifestUpdateErr = msg.err.Error()
+		}
+		return r, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return r, tea.Quit
+		}
+
+		// Пока отчет пишется, остальные клавиши не действуют
+		if r.logFilePath == "" {
+			return r, nil
+		}
+
+		switch msg.String() {
+		case "enter":
+			// Завершаем программу
+			return r, tea.Quit
+
+		case "r":
+			// Перезапуск системы
+			return r, func() tea.Msg {
+				exec.Command("reboot").Run()
+				return restartMsg{}
+			}
+
+		case "e":
+			// Выключение системы
+			return r, func() tea.Msg {
+				exec.Command("poweroff").Run()
+				return shutdownMsg{}
+			}
+
+		case "b":
+			return r, m.request(requestBack)
+		}
+	}
+	return r, nil
+}
+
+func (r reportStage) View(m model) string {
+	if r.logFilePath == "" {
+		// Анимация создания логов
+		progressChars := []string{"◐", "◓", "◑", "◒"}
+		progressText := []string{
+			"Hardware information collected",
+			"System verification completed",
+			"dmidecode data parsed",
+			"Writing log file...",
+		}
+
+		progressLines := make([]string, len(progressText))
+		for i, text := range progressText {
+			if i < m.progressAnimState {
+				progressLines[i] = "■ " + text
+			} else if i == m.progressAnimState {
+				progressLines[i] = progressChars[m.logoAnimState%len(progressChars)] + " " + text
+			} else {
+				progressLines[i] = "□ " + text
+			}
+		}
+
+		return m.overlayView(fmt.Sprintf(
+			"%s\n\n%s\n\n%s",
+			lipgloss.NewStyle().Bold(true).Render("Log Creation"),
+			"Creating system logs...",
+			strings.Join(progressLines, "\n"),
+		))
+	}
+
+	// Создаем красивый вывод содержимого лога
+	logPreview := ""
+	logFile, err := os.ReadFile(r.logFilePath)
+
+	if err == nil {
+		logLines := strings.Split(string(logFile), "\n")
+		// Ограничиваем количество строк для предварительного просмотра
+		previewLines := 10
+		if len(logLines) > previewLines {
+			logLines = logLines[:previewLines]
+			logLines = append(logLines, "... (полный лог сохранен в файле)")
+		}
+
+		logPreview = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#3C3C3C")).
+			Padding(1, 2).
+			Width(m.overlayWidth() - 10).
+			Render(strings.Join(logLines, "\n"))
+	} else {
+		logPreview = "Не удалось прочитать лог-файл: " + err.Error()
+	}
+
+	// Добавляем опции выключения/перезагрузки
+	options := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#EEEEEE")).
+		Render("[E] Выключить систему   [R] Перезагрузить систему   [ENTER] Выход")
+
+	// Итог по проверкам, которые могут забраковать устройство
+	title := lipgloss.NewStyle().Bold(true).Render("Diagnostics Completed Successfully")
+	if failed := m.failedChecks(); len(failed) > 0 {
+		title = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FF5555")).
+			Render("UNIT FAILED: " + strings.Join(failed, ", "))
+	}
+
+	return m.overlayView(fmt.Sprintf(
+		"%s\n\n%s\n\n%s\n\n%s\n\n%s",
+		title,
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#F5D76E")).Render(),
+		fmt.Sprintf("Output file: %s\nJSON report: %s%s", r.logFilePath, r.reportJSONPath, r.manifestUpdateLine(m)),
+		logPreview,
+		options,
+	))
+}
+
+// Отчет не входит в собственные итоги, см. stageResults
+func (reportStage) Result(m model) StageResult {
+	return StageResult{}
+}
+
+// Отчет об устройстве по итогам всех этапов
+func (m model) unitReport(now time.Time) unitReport {
+	serial := m.serialReport()
+	stages := m.stageResults()
+	verdict := "PASS"
+	if !unitPassed(stages) {
+		verdict = "FAIL"
+	}
+	return unitReport{
+		Serial:         serial.Unit,
+		SerialSource:   serial.Source,
+		DMISerial:      m.sysInfo.SerialNumber,
+		Manufacturer:   m.sysInfo.Manufacturer,
+		Product:        m.sysInfo.ProductName,
+		Date:           now,
+		Station:        m.session.Station,
+		Operator:       m.session.Operator,
+		LoginAt:        m.session.LoginAt,
+		Verdict:        verdict,
+		Note:           m.generalNote,
+		Stages:         stages,
+		Attempts:       serial.Attempts,
+		Overrides:      serial.Overrides,
+		Order:          serial.Order,
+		BaseName:       m.reportBaseName(now),
+		Info:           m.sysInfo,
+		DMIDecodeRaw:   m.dmidecodeRaw,
+		SerialCheck:    serial,
+		Video:          m.videoResult(),
+		StorageResults: m.storageResults(),
+		Keyboard:       m.keyboardResult(),
+		Pointer:        m.pointerResult(),
+		Backlight:      m.backlightResult(),
+		Camera:         m.cameraResult(),
+	}
+}
+
+// Имя файла отчета без расширения по шаблону report.file_name
+func (m model) reportBaseName(now time.Time) string {
+	order := ""
+	if manifest := m.unitManifest(); manifest.verified {
+		order = manifest.entry.Order
+	}
+	return strings.NewReplacer(
+		"{station}", fileNamePart(m.session.Station),
+		"{operator}", fileNamePart(m.session.Operator),
+		"{serial}", fileNamePart(m.serialReport().Unit),
+		"{order}", fileNamePart(order),
+		"{timestamp}", now.Format("20060102_150405"),
+	).Replace(m.config.Report.FileName)
+}
+
+// Данные сверки серийного номера для отчета
+func (m model) serialReport() serialReport {
+	s, _ := findStage[serialStage](m)
+	unit, source := m.sysInfo.SerialNumber, serialSourceDMI
+	if m.dmiSerialPlaceholder() {
+		// Заглушка не годится ни для отчета, ни для имени файла
+		switch {
+		case s.matched && s.match.field == "operator":
+			unit, source = s.match.value, serialSourceOperator
+		case s.matched:
+			unit, source = s.match.value, serialSourceDMI+"-"+s.match.field
+		case s.serial != "":
+			unit, source = s.serial, serialSourceEntered
+		}
+	}
+	return serialReport{
+		Unit:      unit,
+		Source:    source,
+		Matched:   s.matched,
+		Entered:   s.serial,
+		Attempts:  s.attempts,
+		Overrides: s.overrides,
+		Order:     m.manifestOrder(),
+	}
+}
+
+// Заказ для отчета: только если устройство найдено и принято на этапе serial
+func (m model) manifestOrder() *ManifestEntry {
+	manifest := m.unitManifest()
+	if !manifest.verified {
+		return nil
+	}
+	return &manifest.entry
+}
+
+// Строка о записи результата в манифест для итогового экрана
+func (r reportStage) manifestUpdateLine(m model) string {
+	switch {
+	case r.manifestUpdateErr != "":
+		return "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).
+			Render("Manifest update failed: "+r.manifestUpdateErr)
+	case r.manifestUpdated:
+		return fmt.Sprintf("\nManifest: order %s updated", m.unitManifest().entry.Order)
+	}
+	return ""
+}
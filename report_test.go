@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSerialReportPlaceholder(t *testing.T) {
+	placeholder := SystemInfo{SerialNumber: "To Be Filled By O.E.M.", BaseboardSerial: "L1HF3AB00XYZ"}
+
+	tests := []struct {
+		name       string
+		info       SystemInfo
+		serial     serialStage
+		wantUnit   string
+		wantSource string
+	}{
+		{
+			name:       "dmi serial",
+			info:       SystemInfo{SerialNumber: "PF3ABC12"},
+			serial:     serialStage{matched: true, serial: "PF3ABC12", match: serialMatch{field: "system", kind: "exact", value: "PF3ABC12"}},
+			wantUnit:   "PF3ABC12",
+			wantSource: "dmi",
+		},
+		{
+			name:       "placeholder field skipped",
+			info:       placeholder,
+			serial:     serialStage{matched: true, serial: "L1HF3AB00XYZ", match: serialMatch{field: "baseboard", kind: "exact", value: "L1HF3AB00XYZ"}},
+			wantUnit:   "L1HF3AB00XYZ",
+			wantSource: "dmi-baseboard",
+		},
+		{
+			name:       "operator provided",
+			info:       SystemInfo{SerialNumber: "Default string"},
+			serial:     serialStage{matched: true, serial: "PF3ABC12", match: serialMatch{field: "operator", kind: "operator-provided", value: "PF3ABC12"}},
+			wantUnit:   "PF3ABC12",
+			wantSource: "operator",
+		},
+		{
+			name:       "placeholder and mismatch",
+			info:       placeholder,
+			serial:     serialStage{serial: "L1HF3AB00XYQ", mismatch: "serial does not match DMI fields baseboard"},
+			wantUnit:   "L1HF3AB00XYQ",
+			wantSource: "entered",
+		},
+		{
+			name:       "placeholder before entry",
+			info:       placeholder,
+			wantUnit:   "To Be Filled By O.E.M.",
+			wantSource: "dmi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := model{
+				config:   StationConfig{Report: ReportConfig{FileName: "{serial}_{timestamp}"}},
+				sysInfo:  tt.info,
+				pipeline: []Stage{tt.serial},
+			}
+			got := m.serialReport()
+			if got.Unit != tt.wantUnit || got.Source != tt.wantSource {
+				t.Errorf("serialReport() = %q from %q, want %q from %q", got.Unit, got.Source, tt.wantUnit, tt.wantSource)
+			}
+
+			// Имя файла строится по тому же номеру
+			now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+			if name, want := m.reportBaseName(now), fileNamePart(tt.wantUnit)+"_20261018_120000"; name != want {
+				t.Errorf("reportBaseName() = %q, want %q", name, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Фаза проверки серийного номера
+type serialPhase int
+
+const (
+	serialPhaseAsk        serialPhase = iota // Ввод серийного номера
+	serialPhaseCheck                         // Сравнение с DMI
+	serialPhaseSuccess                       // Номера совпали
+	serialPhaseError                         // Номера не совпали
+	serialPhaseLocked                        // Превышено число попыток
+	serialPhaseSupervisor                    // Ввод PIN супервизора для разблокировки
+	serialPhaseRejected                      // Номер совпал, но устройства нет в манифесте или оно отгружено
+)
+
+// Этап сверки серийного номера с наклейки с DMI
+type serialStage struct {
+	phase           serialPhase
+	input           textinput.Model
+	serial          string               // Серийный номер после нормализации
+	raw             string               // Ввод оператора или сканера как есть
+	scanned         bool                 // Номер введен сканером
+	corrected       bool                 // Ввод исправлен с русской раскладки
+	matched         bool                 // Совпал ли серийный номер
+	match           serialMatch          // Поле DMI, с которым совпал номер
+	mismatch        string               // Причина несовпадения
+	burst           serialBurstState     // Пачка символов сканера
+	attempts        []SerialAttempt      // Все попытки сверки серийного номера
+	failures        int                  // Несовпадения с последней разблокировки
+	overrides       []SupervisorOverride // Попытки разблокировки супервизором
+	supervisorInput textinput.Model      // Поле ввода PIN супервизора
+	supervisorError string               // Ошибка ввода PIN
+	pinDenials      int                  // Неверные PIN подряд
+	pinRetryAt      time.Time            // Раньше этого времени PIN не проверяется
+	pinChecking     bool                 // PIN проверяется
+	logError        string               // Журнал попыток не записан
+	manifest        manifestState        // Заказ по сверенному номеру
+	manifestChecked bool                 // Поиск по сверенному номеру выполнен
+	lookups         int                  // Номер последнего поиска в манифесте
+}
+
+func newSerialInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "Введите серийный номер"
+	ti.Focus()
+	ti.CharLimit = 128 // Коды GS1 длиннее самого серийного номера
+	ti.Width = 30
+	return ti
+}
+
+func (serialStage) Name() string { return "serial" }
+
+func (s serialStage) Init(m model) (Stage, tea.Cmd) {
+	s.input = newSerialInput()
+	s.supervisorInput = newSupervisorInput()
+	s.burst = serialBurstState{}
+
+	// Ни повторный запуск этапа, ни перезапуск программы не снимают блокировку
+	s.failures = s.restoreFailures(m)
+	s = s.restorePINDenials(m)
+	s.pinChecking = false
+	if s.locked(m.config.Serial) {
+		s.phase = serialPhaseLocked
+		return s, nil
+	}
+	s.phase = serialPhaseAsk
+	return s, nil
+}
+
+// В DMI вместо серийного номера пусто или заглушка
+func (m model) dmiSerialPlaceholder() bool {
+	return isPlaceholderSerial(m.sysInfo.SerialNumber, m.config.Serial.PlaceholderSerials)
+}
+
+// Попытка сверки по текущему вводу
+func (s serialStage) attempt() SerialAttempt {
+	return SerialAttempt{
+		Time:      time.Now(),
+		Entered:   s.serial,
+		Raw:       s.raw,
+		Scanned:   s.scanned,
+		Corrected: s.corrected,
+		Matched:   s.matched,
+	}
+}
+
+// Сохранение попытки в этапе и в журнале попыток
+func (s serialStage) recordAttempt(m model, attempt SerialAttempt) (serialStage, tea.Cmd) {
+	s.attempts = append(slices.Clone(s.attempts), attempt)
+	return s, tea.Batch(updateLogoAnimationCmd, logSerialAttemptCmd(m.session, m.sysInfo.SerialNumber, attempt))
+}
+
+// Отправка введенного номера на сверку
+func (s serialStage) submit(m model, scanned bool) (Stage, tea.Cmd) {
+	s.raw = strings.ReplaceAll(s.input.Value(), gs1SeparatorSymbol, gs1Separator)
+	s.scanned = scanned
+	corrected, changed := correctCyrillicLayout(s.raw)
+	s.corrected = changed
+	s.serial = parseScannedSerial(corrected, m.config.Serial)
+	s.phase = serialPhaseCheck
+	serial := s.serial
+	return s, func() tea.Msg {
+		return checkSerialNumberCmd(serial, m.sysInfo, m.config.Serial)
+	}
+}
+
+// Поиск заказа по сверенному номеру. Ответы на прежние поиски отбрасываются.
+func (s serialStage) lookupManifest(m model) (Stage, tea.Cmd) {
+	s.phase = serialPhaseCheck
+	s.lookups++
+	return s, lookupManifestCmd(m.config.Manifest.Path, []string{s.match.value, s.serial}, true, s.lookups)
+}
+
+func (s serialStage) Update(m model, msg tea.Msg) (Stage, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case serialMatchedMsg:
+		// Серийный номер совпал, показываем сообщение об успехе
+		s.phase = serialPhaseSuccess
+		s.matched = true
+		s.match = msg.match
+		attempt := s.attempt()
+		attempt.Field = msg.match.field
+		s, cmd = s.recordAttempt(m, attempt)
+
+		// С манифестом успех показывается только после поиска заказа
+		if m.config.Manifest.Path != "" {
+			next, lookupCmd := s.lookupManifest(m)
+			return next, tea.Batch(cmd, lookupCmd)
+		}
+		return s, cmd
+
+	case manifestLookupMsg:
+		if s.phase != serialPhaseCheck || msg.seq != s.lookups {
+			return s, nil
+		}
+		s.manifest = msg.state(m.sysInfo)
+		s.manifestChecked = true
+		if s.manifest.verified {
+			s.phase = serialPhaseSuccess
+		} else {
+			s.phase = serialPhaseRejected
+		}
+		return s, nil
+
+	case serialMismatchMsg:
+		// Серийный номер не совпал, показываем ошибку
+		s.phase = serialPhaseError
+		s.serial = msg.entered
+		s.matched = false
+		s.mismatch = msg.reason
+		s.failures++
+		if s.locked(m.config.Serial) {
+			s.phase = serialPhaseLocked
+		}
+		attempt := s.attempt()
+		attempt.Reason = msg.reason
+		return s.recordAttempt(m, attempt)
+
+	case supervisorCheckedMsg:
+		if s.phase != serialPhaseSupervisor || !s.pinChecking {
+			return s, nil
+		}
+		return s.applySupervisorCheck(m, msg.granted)
+
+	case serialLogFailedMsg:
+		s.logError = msg.err.Error()
+		return s, nil
+
+	case serialScanIdleMsg:
+		// Сканер без суффикса ENTER: отправляем, когда пачка закончилась
+		if s.phase == serialPhaseAsk && msg.generation == s.burst.generation {
+			return s.submit(m, true)
+		}
+		return s, nil
+
+	case stageRetryMsg:
+		// Повторный поиск, если манифест успели исправить, иначе новый ввод номера
+		if s.phase == serialPhaseRejected {
+			return s.lookupManifest(m)
+		}
+		s.phase = serialPhaseAsk
+		s.input.SetValue("")
+		return s, s.input.Focus()
+
+	case tea.KeyMsg:
+		if s.phase == serialPhaseSupervisor {
+			return s.updateSupervisor(m, msg)
+		}
+
+		switch msg.String() {
+		case "ctrl+c":
+			return s, tea.Quit
+
+		case "q":
+			// Во время ввода q - обычный символ серийного номера. Заблокированный
+			// этап покидается только с записью отчета о провале ([C]).
+			if s.phase != serialPhaseAsk && s.phase != serialPhaseLocked {
+				return s, tea.Quit
+			}
+
+		case "enter":
+			switch s.phase {
+			case serialPhaseAsk:
+				// Проверяем серийный номер
+				return s.submit(m, s.burst.fromScanner(m.config.Serial))
+
+			case serialPhaseSuccess:
+				// Переходим к созданию логов после успешной проверки серийного номера
+				return s, m.request(requestNext)
+
+			case serialPhaseError, serialPhaseRejected:
+				// Повторная проверка серийника или повторный поиск в манифесте
+				return s, m.request(requestRetry)
+			}
+
+		case "c":
+			if s.phase == serialPhaseError || s.phase == serialPhaseLocked || s.phase == serialPhaseRejected {
+				// Несовпадение попадет в отчет как провал этапа
+				return s, m.request(requestNext)
+			}
+
+		case "s":
+			if s.phase == serialPhaseLocked && m.config.Serial.supervisorEnabled() {
+				s.phase = serialPhaseSupervisor
+				s.supervisorError = ""
+				s.supervisorInput.SetValue("")
+				return s, s.supervisorInput.Focus()
+			}
+
+		case "r":
+			if s.phase == serialPhaseError || s.phase == serialPhaseLocked || s.phase == serialPhaseRejected {
+				// Перезапуск системы
+				return s, func() tea.Msg {
+					exec.Command("reboot").Run()
+					return restartMsg{}
+				}
+			}
+
+		case "e":
+			if s.phase == serialPhaseError || s.phase == serialPhaseLocked || s.phase == serialPhaseRejected {
+				// Выключение системы
+				return s, func() tea.Msg {
+					exec.Command("poweroff").Run()
+					return shutdownMsg{}
+				}
+			}
+
+		case "b":
+			// Во время ввода b - обычный символ серийного номера
+			if s.phase == serialPhaseCheck {
+				// Ответ сверки придет, когда этап уже не на экране: отменяем
+				// поиск и по возвращении снова спрашиваем номер
+				s.lookups++
+				s.phase = serialPhaseAsk
+				s.input.SetValue("")
+				return s, m.request(requestBack)
+			}
+			if s.phase != serialPhaseAsk {
+				return s, m.request(requestBack)
+			}
+		}
+	}
+
+	if s.phase == serialPhaseAsk {
+		var scanCmd tea.Cmd
+		if key, ok := msg.(tea.KeyMsg); ok {
+			scanCmd = s.burst.track(key, m.config.Serial)
+			// Разделитель GS1 от сканера сохраняем в строке
+			if key.Type == tea.KeyCtrlCloseBracket {
+				msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(gs1SeparatorSymbol)}
+			}
+		}
+		s.input, cmd = s.input.Update(msg)
+		return s, tea.Batch(cmd, scanCmd)
+	}
+	return s, nil
+}
+
+func (s serialStage) View(m model) string {
+	errorStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#FF0000")).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#880000")).
+		Padding(1, 2).
+		Align(lipgloss.Center)
+
+	successStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#00AA00")).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#008800")).
+		Padding(1, 2).
+		Align(lipgloss.Center)
+
+	var overlayContent string
+
+	entered := s.serial
+	if s.corrected {
+		entered += " (RU layout)"
+	}
+
+	switch s.phase {
+	case serialPhaseAsk:
+		overlayContent = fmt.Sprintf(
+			"%s\n\n%s\n\n%s",
+			lipgloss.NewStyle().Bold(true).Render("Serial Number Verification"),
+			fmt.Sprintf("System Serial Number: %s", m.sysInfo.SerialNumber),
+			fmt.Sprintf("Please enter Serial Number: %s", s.input.View()),
+		)
+
+		if m.dmiSerialPlaceholder() {
+			overlayContent += "\n\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Render(
+				"DMI serial number is missing or a placeholder.\nEnter the serial number from the sticker, it will be recorded as operator-provided.")
+		}
+
+		// Подсказка, что номер будет проверен в исправленном виде
+		if corrected, changed := correctCyrillicLayout(s.input.Value()); changed {
+			overlayContent += "\n\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("#F5D76E")).Render(
+				"Russian layout detected, checking as: "+parseScannedSerial(corrected, m.config.Serial))
+		}
+
+	case serialPhaseCheck:
+		overlayContent = fmt.Sprintf(
+			"%s\n\n%s\n\n%s",
+			lipgloss.NewStyle().Bold(true).Render("Serial Number Verification"),
+			fmt.Sprintf("Checking %s...", entered),
+			"[B] Return to system information",
+		)
+
+	case serialPhaseSuccess:
+		successBox := successStyle.Width(45).Render(fmt.Sprintf(
+			"Serial numbers match!\n\nDMI %s (%s): %s\nEntered: %s\n\nPress ENTER to continue",
+			s.match.field, s.match.kind, s.match.value, entered,
+		))
+		if s.match.field == "operator" {
+			successBox = successStyle.Width(45).Render(fmt.Sprintf(
+				"Serial number recorded\n\nOperator-provided: %s\n(DMI placeholder %q)\n\nPress ENTER to continue",
+				entered, m.sysInfo.SerialNumber,
+			))
+		}
+
+		if s.manifest.verified {
+			order := "Order: " + s.manifest.entry.Order
+			if s.manifest.entry.Customer != "" {
+				order += "\nCustomer: " + s.manifest.entry.Customer
+			}
+			successBox += "\n\n" + order
+		}
+
+		overlayContent = fmt.Sprintf(
+			"%s\n\n%s\n\n%s\n\n%s",
+			lipgloss.NewStyle().Bold(true).Render("Serial Number Verification Successful"),
+			lipgloss.NewStyle().Foreground(lipgloss.Color("#F5D76E")).Render(),
+			successBox,
+			"[B] Return to system information",
+		)
+
+	case serialPhaseError:
+		errorBox := errorStyle.Width(45).Render(fmt.Sprintf(
+			"Serial numbers DO NOT match!\n\nSystem: %s\nEntered: %s\n%s\n\n[R] Restart system\n[E] Shutdown system\n[C] Write failed report\n[ENTER] Try again",
+			m.sysInfo.SerialNumber, entered, s.mismatch,
+		))
+		overlayContent = fmt.Sprintf(
+			"%s\n\n%s\n\n%s\n\n%s",
+			lipgloss.NewStyle().Bold(true).Render("Serial Number Verification Failed"),
+			lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Render(),
+			errorBox,
+			"[B] Return to system information",
+		)
+
+	case serialPhaseRejected:
+		errorBox := errorStyle.Width(45).Render(fmt.Sprintf(
+			"UNIT REJECTED\n\nSerial: %s\n%s\n\n[C] Write failed report\n[R] Restart system\n[E] Shutdown system\n[ENTER] Check manifest again",
+			entered, s.manifest.problem,
+		))
+		overlayContent = fmt.Sprintf(
+			"%s\n\n%s\n\n%s",
+			lipgloss.NewStyle().Bold(true).Render("Manifest Check Failed"),
+			errorBox,
+			"[B] Return to system information",
+		)
+
+	case serialPhaseLocked:
+		options := "[C] Write failed report\n[R] Restart system\n[E] Shutdown system"
+		if m.config.Serial.supervisorEnabled() {
+			options = "[S] Supervisor unlock\n" + options
+		}
+		errorBox := errorStyle.Width(45).Render(fmt.Sprintf(
+			"UNIT LOCKED\n\n%d failed serial number attempts\nLast entered: %s\n\n%s",
+			s.failures, entered, options,
+		))
+		overlayContent = fmt.Sprintf(
+			"%s\n\n%s\n\n%s",
+			lipgloss.NewStyle().Bold(true).Render("Serial Number Verification Locked"),
+			errorBox,
+			"[B] Return to system information",
+		)
+
+	case serialPhaseSupervisor:
+		overlayContent = fmt.Sprintf(
+			"%s\n\n%s\n\n%s\n\n%s",
+			lipgloss.NewStyle().Bold(true).Render("Supervisor Unlock"),
+			fmt.Sprintf("%s: %s", m.config.Serial.supervisorSecretName(), s.supervisorInput.View()),
+			lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Render(s.supervisorError),
+			"[ENTER] Unlock   [ESC] Cancel",
+		)
+	}
+
+	if s.logError != "" {
+		overlayContent += "\n\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Render(
+			"Warning: serial attempts log not written: "+s.logError)
+	}
+
+	return m.overlayView(overlayContent)
+}
+
+func (s serialStage) Result(m model) StageResult {
+	var res StageResult
+	if s.phase == serialPhaseAsk || s.phase == serialPhaseCheck {
+		return res
+	}
+
+	res.value("entered", "%s", s.serial)
+	res.value("raw input", "%q", s.raw)
+	if s.corrected {
+		res.value("layout correction", "ЙЦУКЕН -> QWERTY")
+	}
+	if s.scanned {
+		res.value("input", "barcode scanner")
+	} else {
+		res.value("input", "keyboard")
+	}
+	res.value("system", "%s", m.sysInfo.SerialNumber)
+	if m.dmiSerialPlaceholder() {
+		res.value("dmi serial", "placeholder")
+	}
+	if s.matched {
+		res.value("matched field", "%s (%s): %s", s.match.field, s.match.kind, s.match.value)
+	} else {
+		res.fail("serial number mismatch: %s", s.mismatch)
+	}
+	if manifest := m.unitManifest(); manifest.found {
+		res.value("work order", "%s", manifest.entry.Order)
+		if manifest.entry.Customer != "" {
+			res.value("customer", "%s", manifest.entry.Customer)
+		}
+		if manifest.entry.Model != "" {
+			res.value("expected model", "%s", manifest.entry.Model)
+		}
+	}
+	if s.phase == serialPhaseRejected {
+		res.fail("manifest: %s", s.manifest.problem)
+	}
+	res.value("attempts", "%d", len(s.attempts))
+	for _, override := range s.overrides {
+		status := "denied"
+		if override.Granted {
+			status = "granted"
+		}
+		res.value("supervisor override", "%s at %s after %d failures",
+			status, override.Time.Format("15:04:05"), override.Failures)
+	}
+	if s.locked(m.config.Serial) {
+		res.fail("serial check locked after %d failed attempts", s.failures)
+	}
+	res.settle()
+	return res
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Пауза после пачки символов сканера, по которой ввод отправляется сам
+const scanIdleTimeout = 150 * time.Millisecond
+
+// Разделитель полей GS1 (FNC1), сканер передает его как ctrl+]
+const gs1Separator = "\x1d"
+
+// Поле ввода не принимает управляющие символы, поэтому разделитель
+// показывается символом ␝ и заменяется обратно при отправке
+const gs1SeparatorSymbol = "␝"
+
+// Пачка быстрых нажатий: так вводит HID-сканер штрихкодов
+type serialBurstState struct {
+	last       time.Time
+	length     int // Символов в текущей пачке
+	generation int // Номер последнего нажатия, для таймера автоотправки
+}
+
+// Таймер автоотправки после пачки символов сканера
+type serialScanIdleMsg struct {
+	generation int
+}
+
+// Учет нажатия в поле серийного номера. Возвращает таймер автоотправки,
+// если пачка похожа на сканер.
+func (b *serialBurstState) track(msg tea.KeyMsg, cfg SerialConfig) tea.Cmd {
+	b.generation++
+
+	n := len(msg.Runes)
+	if msg.Type == tea.KeyCtrlCloseBracket {
+		n = 1
+	}
+	if n == 0 {
+		// Редактирование вручную прерывает пачку
+		b.length = 0
+		return nil
+	}
+
+	now := time.Now()
+	if now.Sub(b.last) <= cfg.scanMaxGap() {
+		b.length += n
+	} else {
+		b.length = n
+	}
+	b.last = now
+
+	if !cfg.ScannerAutoSubmit || b.length < cfg.ScannerMinLength {
+		return nil
+	}
+	generation := b.generation
+	return tea.Tick(scanIdleTimeout, func(time.Time) tea.Msg {
+		return serialScanIdleMsg{generation: generation}
+	})
+}
+
+// Ввод пришел со сканера: пачка достаточной длины только что закончилась
+func (b serialBurstState) fromScanner(cfg SerialConfig) bool {
+	return b.length >= cfg.ScannerMinLength && time.Since(b.last) <= scanIdleTimeout
+}
+
+// Приведение серийного номера к виду для сравнения: без пробелов, в верхнем регистре
+func normalizeSerial(s string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s))
+}
+
+// Исправление ввода при активной русской раскладке: кириллица заменяется
+// латиницей на тех же клавишах (ФВЙЛИИ2Ф -> ADQKBB2A). Знаки препинания
+// исправляются только вместе с буквами, сами по себе они бывают в номере.
+func correctCyrillicLayout(s string) (string, bool) {
+	if !strings.ContainsFunc(s, func(r rune) bool { return unicode.Is(unicode.Cyrillic, r) }) {
+		return s, false
+	}
+	return strings.Map(func(r rune) rune {
+		latin, ok := russianKeyMap[unicode.ToLower(r)]
+		if !ok {
+			return r
+		}
+		if unicode.IsUpper(r) {
+			return unicode.ToUpper(latin)
+		}
+		return latin
+	}, s), true
+}
+
+// Серийный номер из введенной или отсканированной строки: поле 21 GS1,
+// нормализация и снятие префиксов и суффиксов из конфигурации
+func parseScannedSerial(raw string, cfg SerialConfig) string {
+	s := strings.TrimSpace(raw)
+	if cfg.GS1 {
+		if serial, ok := gs1Serial(s); ok {
+			s = serial
+		}
+	}
+
+	s = normalizeSerial(s)
+	for _, prefix := range cfg.StripPrefixes {
+		if p := normalizeSerial(prefix); p != "" && strings.HasPrefix(s, p) {
+			s = strings.TrimPrefix(s, p)
+			break
+		}
+	}
+	for _, suffix := range cfg.StripSuffixes {
+		if p := normalizeSerial(suffix); p != "" && strings.HasSuffix(s, p) {
+			s = strings.TrimSuffix(s, p)
+			break
+		}
+	}
+	return s
+}
+
+// Полная длина элемента GS1 с AI, длина которого задана первыми двумя цифрами
+// (GS1 General Specifications, таблица предопределенных длин)
+var gs1PredefinedLength = map[string]int{
+	"00": 20, "01": 16, "02": 16, "03": 16, "04": 18,
+	"11": 8, "12": 8, "13": 8, "14": 8, "15": 8, "16": 8, "17": 8, "18": 8, "19": 8,
+	"20": 4, "31": 10, "32": 10, "33": 10, "34": 10, "35": 10, "36": 10, "41": 16,
+}
+
+// Форма GS1 для чтения человеком: (01)04601234567890(21)ABC123
+var gs1HumanReadable = regexp.MustCompile(`\((\d{2,4})\)([^(]*)`)
+
+// Серийный номер (AI 21) из строки GS1. Строка считается GS1 только при явных
+// признаках: идентификатор символики ]C1/]d2/]Q3/]e0, разделитель GS или AI в скобках.
+func gs1Serial(s string) (string, bool) {
+	if strings.HasPrefix(s, "(") {
+		for _, match := range gs1HumanReadable.FindAllStringSubmatch(s, -1) {
+			if match[1] == "21" {
+				return match[2], true
+			}
+		}
+		return "", false
+	}
+
+	symbology := false
+	for _, id := range []string{"]C1", "]d2", "]Q3", "]e0"} {
+		if strings.HasPrefix(s, id) {
+			s, symbology = s[len(id):], true
+			break
+		}
+	}
+	if !symbology && !strings.Contains(s, gs1Separator) {
+		return "", false
+	}
+
+	s = strings.TrimPrefix(s, gs1Separator)
+	for len(s) >= 2 {
+		if n, ok := gs1PredefinedLength[s[:2]]; ok {
+			if len(s) < n {
+				return "", false
+			}
+			s = strings.TrimPrefix(s[n:], gs1Separator)
+			continue
+		}
+
+		// Элемент переменной длины заканчивается разделителем или концом строки
+		element, rest, _ := strings.Cut(s, gs1Separator)
+		if serial, ok := strings.CutPrefix(element, "21"); ok {
+			return serial, true
+		}
+		s = rest
+	}
+	return "", false
+}
+
+// Попытка сверки серийного номера
+type SerialAttempt struct {
+	Time      time.Time `json:"time"`
+	Entered   string    `json:"entered"` // Номер после нормализации
+	Raw       string    `json:"raw"`     // Ввод как есть
+	Scanned   bool      `json:"scanned"`
+	Corrected bool      `json:"layout_corrected"`
+	Matched   bool      `json:"matched"`
+	Field     string    `json:"field,omitempty"`  // Совпавшее поле DMI
+	Reason    string    `json:"reason,omitempty"` // Причина несовпадения
+}
+
+// Исход попытки для журнала
+func (a SerialAttempt) outcome() string {
+	if a.Matched {
+		return "match " + a.Field
+	}
+	return "mismatch: " + a.Reason
+}
+
+// Журнал попыток не записан. Проверка продолжается, оператор видит предупреждение.
+type serialLogFailedMsg struct {
+	err error
+}
+
+// Журнал попыток сверки пишется сразу, чтобы несовпадения сохранились,
+// даже если устройство выключат, не дойдя до отчета
+func logSerialAttemptCmd(session operatorSession, system string, attempt SerialAttempt) tea.Cmd {
+	return func() tea.Msg {
+		if err := appendSerialAttempt(session, system, attempt); err != nil {
+			return serialLogFailedMsg{err}
+		}
+		return nil
+	}
+}
+
+func appendSerialAttempt(session operatorSession, system string, attempt SerialAttempt) error {
+	return appendSerialLog(fmt.Sprintf("%s station=%q operator=%q system=%q entered=%q raw=%q %s",
+		attempt.Time.Format(time.RFC3339), session.Station, session.Operator, system, attempt.Entered, attempt.Raw, attempt.outcome()))
+}
+
+// Строка в журнал попыток сверки
+func appendSerialLog(line string) error {
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(serialAttemptsLogPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, line)
+	return err
+}
+
+func serialAttemptsLogPath() string {
+	return filepath.Join(logsDir, "serial_attempts.log")
+}
@@ -0,0 +1,89 @@
+package main
+
+import "testing"
+
+func TestGS1Serial(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"plain serial", "ABC123", "", false},
+		{"digits only", "0104601234567890", "", false},
+		{"human readable", "(01)04601234567890(21)ABC123", "ABC123", true},
+		{"human readable without serial", "(01)04601234567890(17)251231", "", false},
+		{"symbology identifier", "]C10104601234567890" + "21ABC123", "ABC123", true},
+		{"data matrix identifier", "]d2" + "17251231" + "21SN9", "SN9", true},
+		{"separator after serial", "0104601234567890" + "21ABC123" + gs1Separator + "10LOT1", "ABC123", true},
+		{"leading separator", gs1Separator + "21ABC123" + gs1Separator + "10LOT1", "ABC123", true},
+		{"variable element before serial", "]Q3" + "10LOT1" + gs1Separator + "21XYZ", "XYZ", true},
+		{"truncated predefined element", "]C1" + "010460", "", false},
+		{"no serial element", "]C1" + "0104601234567890" + "10LOT1", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := gs1Serial(tt.in)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("gs1Serial(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseScannedSerial(t *testing.T) {
+	labels := SerialConfig{StripPrefixes: []string{"S/N:", "SN"}, StripSuffixes: []string{"-r"}}
+
+	tests := []struct {
+		name string
+		raw  string
+		cfg  SerialConfig
+		want string
+	}{
+		{"normalized", " abc 123\t", SerialConfig{}, "ABC123"},
+		{"label prefix", "S/N: abc123", labels, "ABC123"},
+		{"only first prefix", "SNSN1", labels, "SN1"},
+		{"label suffix", "abc123-R", labels, "ABC123"},
+		{"empty prefix ignored", "abc", SerialConfig{StripPrefixes: []string{" "}}, "ABC"},
+		{"gs1 serial", "(01)04601234567890(21)abc123", SerialConfig{GS1: true}, "ABC123"},
+		{"gs1 disabled", "(01)04601234567890(21)abc123", SerialConfig{}, "(01)04601234567890(21)ABC123"},
+		{"gs1 plain serial", "abc123", SerialConfig{GS1: true}, "ABC123"},
+		{"gs1 then prefix", "]C1" + "21SNABC", SerialConfig{GS1: true, StripPrefixes: []string{"SN"}}, "ABC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseScannedSerial(tt.raw, tt.cfg); got != tt.want {
+				t.Errorf("parseScannedSerial(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCorrectCyrillicLayout(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		want        string
+		wantChanged bool
+	}{
+		{"latin unchanged", "ADQKBB2A", "ADQKBB2A", false},
+		{"latin punctuation kept", "AB.12,3", "AB.12,3", false},
+		{"uppercase", "ФВЙЛИИ2Ф", "ADQKBB2A", true},
+		{"lowercase", "фвйл", "adqk", true},
+		{"mixed case", "Фв", "Ad", true},
+		{"punctuation keys with letters", "фю1б", "a.1,", true},
+		{"other symbols kept", "ФВ-12/Й", "AD-12/Q", true},
+		{"mixed layouts", "ABфв12", "ABad12", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := correctCyrillicLayout(tt.in)
+			if got != tt.want || changed != tt.wantChanged {
+				t.Errorf("correctCyrillicLayout(%q) = %q, %v, want %q, %v", tt.in, got, changed, tt.want, tt.wantChanged)
+			}
+		})
+	}
+}
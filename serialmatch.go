@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Поля DMI, с которыми можно сверять серийный номер с наклейки
+var serialFields = []string{"system", "baseboard", "chassis", "uuid"}
+
+// Значение поля DMI для сверки
+func (s SystemInfo) dmiSerial(field string) string {
+	switch field {
+	case "system":
+		return s.SerialNumber
+	case "baseboard":
+		return s.BaseboardSerial
+	case "chassis":
+		return s.ChassisSerial
+	case "uuid":
+		return s.UUID
+	}
+	return ""
+}
+
+// Заглушки, которые прошивки записывают вместо серийного номера
+var placeholderSerials = []string{
+	"To Be Filled By O.E.M.", "Default string", "System Serial Number", "Chassis Serial Number",
+	"Base Board Serial Number", "Not Specified", "Not Applicable", "None", "N/A", "OEM",
+	"Invalid", "Default", "Serial", "0123456789", "123456789", "1234567890",
+}
+
+// Серийный номер пустой, из списка заглушек или из одного повторяющегося
+// символа (00000000, XXXXXXXX)
+func isPlaceholderSerial(serial string, extra []string) bool {
+	s := normalizeSerial(serial)
+	if s == "" || strings.Count(s, s[:1]) == len(s) {
+		return true
+	}
+	for _, placeholder := range append(placeholderSerials, extra...) {
+		if s == normalizeSerial(placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// Правила сверки для устройства: общие правила и правило производителя
+type serialRules struct {
+	fields      []string
+	format      *regexp.Regexp // Формат номера с наклейки, nil - любой
+	allowPrefix bool
+	allowSuffix bool
+	minPartial  int
+}
+
+// Правила сверки по производителю из DMI. Правило производителя выбирается
+// по подстроке Manufacturer, как переопределения моделей по Product Name.
+func (c SerialConfig) rulesFor(manufacturer string) serialRules {
+	rules := serialRules{
+		fields:      c.Fields,
+		allowPrefix: c.AllowPrefix,
+		allowSuffix: c.AllowSuffix,
+		minPartial:  c.MinPartialLength,
+	}
+
+	for substr, vendor := range c.Vendors {
+		if !strings.Contains(strings.ToLower(manufacturer), strings.ToLower(substr)) {
+			continue
+		}
+		if len(vendor.Fields) > 0 {
+			rules.fields = vendor.Fields
+		}
+		if vendor.Format != "" {
+			// Формат проверен при загрузке конфигурации
+			rules.format = regexp.MustCompile(vendor.Format)
+		}
+		rules.allowPrefix = rules.allowPrefix || vendor.AllowPrefix
+		rules.allowSuffix = rules.allowSuffix || vendor.AllowSuffix
+		break
+	}
+	return rules
+}
+
+// Итог сверки серийного номера
+type serialMatch struct {
+	field string // Совпавшее поле DMI
+	kind  string // exact, prefix или suffix
+	value string // Значение поля
+}
+
+// Сверка введенного номера с полями DMI. Формат производителя проверяется
+// до сверки; если в нем есть группа, сверяется только она.
+func matchSerial(entered string, info SystemInfo, cfg SerialConfig) (serialMatch, error) {
+	rules := cfg.rulesFor(info.Manufacturer)
+
+	if rules.format != nil {
+		groups := rules.format.FindStringSubmatch(entered)
+		if groups == nil {
+			return serialMatch{}, fmt.Errorf("serial does not match vendor format %s", rules.format)
+		}
+		if len(groups) > 1 {
+			entered = groups[1]
+		}
+	}
+
+	// Заглушки не сверяются: 0123456789 совпал бы с любым номером по началу
+	var fields []string
+	for _, field := range rules.fields {
+		if !isPlaceholderSerial(info.dmiSerial(field), cfg.PlaceholderSerials) {
+			fields = append(fields, field)
+		}
+	}
+
+	// Сверять не с чем, номер с наклейки принимается со слов оператора
+	if len(fields) == 0 {
+		if entered == "" {
+			return serialMatch{}, fmt.Errorf("serial number is empty")
+		}
+		return serialMatch{field: "operator", kind: "operator-provided", value: entered}, nil
+	}
+	rules.fields = fields
+
+	// Точное совпадение важнее частичного, поэтому сначала проверяем его по всем полям
+	for _, field := range rules.fields {
+		if value := normalizeSerial(info.dmiSerial(field)); value == entered {
+			return serialMatch{field: field, kind: "exact", value: value}, nil
+		}
+	}
+
+	if len(entered) < rules.minPartial {
+		return serialMatch{}, fmt.Errorf("serial does not match DMI fields %s", strings.Join(rules.fields, ", "))
+	}
+	for _, field := range rules.fields {
+		value := normalizeSerial(info.dmiSerial(field))
+		if len(value) < rules.minPartial {
+			continue
+		}
+		switch {
+		case rules.allowPrefix && (strings.HasPrefix(value, entered) || strings.HasPrefix(entered, value)):
+			return serialMatch{field: field, kind: "prefix", value: value}, nil
+		case rules.allowSuffix && (strings.HasSuffix(value, entered) || strings.HasSuffix(entered, value)):
+			return serialMatch{field: field, kind: "suffix", value: value}, nil
+		}
+	}
+	return serialMatch{}, fmt.Errorf("serial does not match DMI fields %s", strings.Join(rules.fields, ", "))
+}
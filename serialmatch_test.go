@@ -0,0 +1,239 @@
+package main
+
+import "testing"
+
+func TestMatchSerial(t *testing.T) {
+	info := SystemInfo{
+		Manufacturer:    "LENOVO",
+		SerialNumber:    "PF3ABC12",
+		BaseboardSerial: "L1HF3AB00XYZ",
+		ChassisSerial:   "CH998877",
+		UUID:            "4C4C4544-0042-3010-8052-B4C04F4E3332",
+	}
+	fields := []string{"system", "baseboard"}
+
+	tests := []struct {
+		name    string
+		entered string
+		info    SystemInfo
+		cfg     SerialConfig
+		want    serialMatch
+		wantErr bool
+	}{
+		{
+			name:    "exact system",
+			entered: "PF3ABC12",
+			cfg:     SerialConfig{Fields: fields},
+			want:    serialMatch{field: "system", kind: "exact", value: "PF3ABC12"},
+		},
+		{
+			name:    "exact baseboard",
+			entered: "L1HF3AB00XYZ",
+			cfg:     SerialConfig{Fields: fields},
+			want:    serialMatch{field: "baseboard", kind: "exact", value: "L1HF3AB00XYZ"},
+		},
+		{
+			name:    "field not configured",
+			entered: "CH998877",
+			cfg:     SerialConfig{Fields: fields},
+			wantErr: true,
+		},
+		{
+			name:    "uuid field",
+			entered: "4C4C4544-0042-3010-8052-B4C04F4E3332",
+			cfg:     SerialConfig{Fields: []string{"uuid"}},
+			want:    serialMatch{field: "uuid", kind: "exact", value: "4C4C4544-0042-3010-8052-B4C04F4E3332"},
+		},
+		{
+			name:    "prefix not allowed",
+			entered: "PF3ABC",
+			cfg:     SerialConfig{Fields: fields, MinPartialLength: 6},
+			wantErr: true,
+		},
+		{
+			name:    "prefix",
+			entered: "PF3ABC",
+			cfg:     SerialConfig{Fields: fields, AllowPrefix: true, MinPartialLength: 6},
+			want:    serialMatch{field: "system", kind: "prefix", value: "PF3ABC12"},
+		},
+		{
+			name:    "label longer than DMI",
+			entered: "PF3ABC12X",
+			cfg:     SerialConfig{Fields: fields, AllowPrefix: true, MinPartialLength: 6},
+			want:    serialMatch{field: "system", kind: "prefix", value: "PF3ABC12"},
+		},
+		{
+			name:    "suffix",
+			entered: "3AB00XYZ",
+			cfg:     SerialConfig{Fields: fields, AllowSuffix: true, MinPartialLength: 6},
+			want:    serialMatch{field: "baseboard", kind: "suffix", value: "L1HF3AB00XYZ"},
+		},
+		{
+			name:    "partial too short",
+			entered: "PF3",
+			cfg:     SerialConfig{Fields: fields, AllowPrefix: true, MinPartialLength: 6},
+			wantErr: true,
+		},
+		{
+			name:    "exact wins over earlier partial",
+			entered: "ABC123456",
+			info:    SystemInfo{SerialNumber: "ABC1234567", BaseboardSerial: "ABC123456"},
+			cfg:     SerialConfig{Fields: fields, AllowPrefix: true, MinPartialLength: 6},
+			want:    serialMatch{field: "baseboard", kind: "exact", value: "ABC123456"},
+		},
+		{
+			name:    "vendor fields",
+			entered: "CH998877",
+			cfg: SerialConfig{Fields: fields, Vendors: map[string]SerialVendorRule{
+				"lenovo": {Fields: []string{"chassis"}},
+			}},
+			want: serialMatch{field: "chassis", kind: "exact", value: "CH998877"},
+		},
+		{
+			name:    "vendor format group",
+			entered: "1SABCDPF3ABC12",
+			cfg: SerialConfig{Fields: fields, Vendors: map[string]SerialVendorRule{
+				"lenovo": {Format: `^1S\w{4}(\w{8})$`},
+			}},
+			want: serialMatch{field: "system", kind: "exact", value: "PF3ABC12"},
+		},
+		{
+			name:    "vendor format mismatch",
+			entered: "PF3ABC12",
+			cfg: SerialConfig{Fields: fields, Vendors: map[string]SerialVendorRule{
+				"lenovo": {Format: `^1S\w{12}$`},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "vendor allows prefix",
+			entered: "PF3ABC",
+			cfg: SerialConfig{Fields: fields, MinPartialLength: 6, Vendors: map[string]SerialVendorRule{
+				"lenovo": {AllowPrefix: true},
+			}},
+			want: serialMatch{field: "system", kind: "prefix", value: "PF3ABC12"},
+		},
+		{
+			name:    "other vendor rule ignored",
+			entered: "CH998877",
+			cfg: SerialConfig{Fields: fields, Vendors: map[string]SerialVendorRule{
+				"dell": {Fields: []string{"chassis"}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit := tt.info
+			if unit.SerialNumber == "" {
+				unit = info
+			}
+			got, err := matchSerial(tt.entered, unit, tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("matchSerial(%q) = %+v, want error", tt.entered, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("matchSerial(%q): %v", tt.entered, err)
+			}
+			if got != tt.want {
+				t.Errorf("matchSerial(%q) = %+v, want %+v", tt.entered, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPlaceholderSerial(t *testing.T) {
+	tests := []struct {
+		serial string
+		extra  []string
+		want   bool
+	}{
+		{"", nil, true},
+		{"  ", nil, true},
+		{"To Be Filled By O.E.M.", nil, true},
+		{"to be filled by o.e.m.", nil, true},
+		{"Default string", nil, true},
+		{"0123456789", nil, true},
+		{"00000000", nil, true},
+		{"XXXXXXXX", nil, true},
+		{"PF3ABC12", nil, false},
+		{"0123456780", nil, false},
+		{"TBD", nil, false},
+		{"tbd", []string{"TBD"}, true},
+	}
+
+	for _, tt := range tests {
+		if got := isPlaceholderSerial(tt.serial, tt.extra); got != tt.want {
+			t.Errorf("isPlaceholderSerial(%q, %q) = %v, want %v", tt.serial, tt.extra, got, tt.want)
+		}
+	}
+}
+
+func TestMatchSerialPlaceholders(t *testing.T) {
+	tests := []struct {
+		name    string
+		entered string
+		info    SystemInfo
+		cfg     SerialConfig
+		want    serialMatch
+		wantErr bool
+	}{
+		{
+			name:    "placeholder field skipped",
+			entered: "L1HF3AB00XYZ",
+			info:    SystemInfo{SerialNumber: "To Be Filled By O.E.M.", BaseboardSerial: "L1HF3AB00XYZ"},
+			cfg:     SerialConfig{Fields: []string{"system", "baseboard"}},
+			want:    serialMatch{field: "baseboard", kind: "exact", value: "L1HF3AB00XYZ"},
+		},
+		{
+			name:    "placeholder never matched by prefix",
+			entered: "0123",
+			info:    SystemInfo{SerialNumber: "0123456789", BaseboardSerial: "L1HF3AB00XYZ"},
+			cfg:     SerialConfig{Fields: []string{"system", "baseboard"}, AllowPrefix: true, MinPartialLength: 2},
+			wantErr: true,
+		},
+		{
+			name:    "operator provided when all fields are placeholders",
+			entered: "PF3ABC12",
+			info:    SystemInfo{SerialNumber: "Default string", BaseboardSerial: ""},
+			cfg:     SerialConfig{Fields: []string{"system", "baseboard"}},
+			want:    serialMatch{field: "operator", kind: "operator-provided", value: "PF3ABC12"},
+		},
+		{
+			name:    "configured placeholder",
+			entered: "PF3ABC12",
+			info:    SystemInfo{SerialNumber: "TBD"},
+			cfg:     SerialConfig{Fields: []string{"system"}, PlaceholderSerials: []string{"tbd"}},
+			want:    serialMatch{field: "operator", kind: "operator-provided", value: "PF3ABC12"},
+		},
+		{
+			name:    "empty serial not accepted",
+			entered: "",
+			info:    SystemInfo{SerialNumber: "00000000"},
+			cfg:     SerialConfig{Fields: []string{"system"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchSerial(tt.entered, tt.info, tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("matchSerial(%q) = %+v, want error", tt.entered, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("matchSerial(%q): %v", tt.entered, err)
+			}
+			if got != tt.want {
+				t.Errorf("matchSerial(%q) = %+v, want %+v", tt.entered, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,490 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Этап проверки в конвейере станции. Этап - значение со своим состоянием:
+// Init и Update возвращают обновленный этап, конвейер хранит его на месте
+// прежнего. Модель этап только читает, переходы запрашивает командой request.
+type Stage interface {
+	Name() string                                 // Имя этапа в конфигурации и отчете
+	Init(m model) (Stage, tea.Cmd)                // Запуск этапа
+	Update(m model, msg tea.Msg) (Stage, tea.Cmd) // Сообщения, пока этап активен
+	View(m model) string                          // Экран этапа
+	Result(m model) StageResult                   // Итог этапа
+}
+
+// Запрос этапа к конвейеру
+type pipelineRequest int
+
+const (
+	requestNext    pipelineRequest = iota // Этап завершен, переход к следующему
+	requestBack                           // Возврат к экрану информации
+	requestRestart                        // Возврат к информации, этап начнется заново
+	requestRetry                          // Повтор проверки после заметки оператора
+)
+
+// Запрос этапа с номером stage. Запрос этапа, который уже не текущий,
+// отбрасывается, поэтому двойной ENTER не пропустит следующий этап.
+type pipelineRequestMsg struct {
+	stage   int
+	request pipelineRequest
+}
+
+// Повтор проверки: этап получает его после заметки оператора по requestRetry
+type stageRetryMsg struct{}
+
+// Статус этапа в отчете. Пустой статус - этап еще не дал итога.
+type StageStatus string
+
+const (
+	StagePass    StageStatus = "pass"
+	StageFail    StageStatus = "fail"
+	StageSkip    StageStatus = "skip"    // Этап не выполнялся или проверять было нечего
+	StageError   StageStatus = "error"   // Этап не смог выполнить проверку
+	StageAborted StageStatus = "aborted" // Этап не завершен: оставлен или не запускался
+)
+
+// Измеренное значение этапа
+type StageValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Итог этапа для отчета и итогового экрана. Stage возвращает статус, значения
+// и причины, время и число повторов добавляет конвейер.
+type StageResult struct {
+	Stage    string        `json:"stage"`
+	Status   StageStatus   `json:"status"`
+	Started  time.Time     `json:"started,omitzero"`
+	Finished time.Time     `json:"finished,omitzero"`
+	Duration time.Duration `json:"duration_ns"`
+	Retries  int           `json:"retries"`
+	Values   []StageValue  `json:"values,omitempty"`
+	Reasons  []string      `json:"reasons,omitempty"` // Причины провала, ошибки или пропуска
+	Notes    []StageNote   `json:"notes,omitempty"`   // Заметки оператора и коды дефектов
+}
+
+// Добавление измеренного значения
+func (r *StageResult) value(name, format string, args ...any) {
+	r.Values = append(r.Values, StageValue{Name: name, Value: fmt.Sprintf(format, args...)})
+}
+
+// Добавление причины провала
+func (r *StageResult) fail(format string, args ...any) {
+	r.Status = StageFail
+	r.Reasons = append(r.Reasons, fmt.Sprintf(format, args...))
+}
+
+// Статус по итогам проверок: провал, если была хотя бы одна причина
+func (r *StageResult) settle() {
+	if r.Status == "" && len(r.Reasons) == 0 {
+		r.Status = StagePass
+	}
+}
+
+// Этап засчитывается в итоговый вердикт как пройденный
+func (r StageResult) ok() bool {
+	return r.Status == StagePass || r.Status == StageSkip
+}
+
+// Время запуска, завершения и число повторов этапа
+type stageTiming struct {
+	started  time.Time
+	finished time.Time
+	retries  int
+}
+
+// Этапы, которые можно указать в конфигурации станции
+var stageCatalog = map[string]Stage{
+	"storage":   storageStage{},
+	"video":     videoStage{},
+	"keyboard":  keyboardStage{},
+	"pointer":   pointerStage{},
+	"backlight": backlightStage{},
+	"camera":    cameraStage{},
+	"serial":    serialStage{},
+}
+
+// Порядок этапов, если он не задан в конфигурации: необязательные этапы
+// включаются своими флагами enabled
+func defaultStageNames(cfg StationConfig) []string {
+	var names []string
+	if cfg.StorageTest.Enabled {
+		names = append(names, "storage")
+	}
+	names = append(names, "video")
+	if cfg.Keyboard.Enabled {
+		names = append(names, "keyboard")
+	}
+	if cfg.Pointer.Enabled {
+		names = append(names, "pointer")
+	}
+	if cfg.Backlight.Enabled {
+		names = append(names, "backlight")
+	}
+	if cfg.Camera.Enabled {
+		names = append(names, "camera")
+	}
+	return append(names, "serial")
+}
+
+// Экран информации в конвейере идет сразу после входа оператора
+const infoStageIndex = 1
+
+// Сборка конвейера: вход оператора, экран информации, этапы из конфигурации,
+// запись отчета
+func buildPipeline(cfg StationConfig) ([]Stage, error) {
+	names := cfg.Stages
+	if len(names) == 0 {
+		names = defaultStageNames(cfg)
+	}
+
+	pipeline := []Stage{loginStage{}, infoStage{}}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		stage, ok := stageCatalog[name]
+		if !ok {
+			return nil, fmt.Errorf("неизвестный этап %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("этап %q указан дважды", name)
+		}
+		seen[name] = true
+		pipeline = append(pipeline, stage)
+	}
+	return append(pipeline, reportStage{}), nil
+}
+
+// Текущий этап конвейера
+func (m model) currentStage() Stage {
+	return m.pipeline[m.stage]
+}
+
+// Сохранение обновленного текущего этапа
+func (m model) setStage(stage Stage) model {
+	pipeline := slices.Clone(m.pipeline)
+	pipeline[m.stage] = stage
+	m.pipeline = pipeline
+	return m
+}
+
+// Передача сообщения текущему этапу
+func (m model) updateStage(msg tea.Msg) (tea.Model, tea.Cmd) {
+	stage, cmd := m.currentStage().Update(m, msg)
+	return m.setStage(stage), cmd
+}
+
+// Этап конвейера по типу, например для данных теста в отчете
+func findStage[S Stage](m model) (S, bool) {
+	for _, stage := range m.pipeline {
+		if s, ok := stage.(S); ok {
+			return s, true
+		}
+	}
+	var zero S
+	return zero, false
+}
+
+// Команда запроса от текущего этапа к конвейеру
+func (m model) request(request pipelineRequest) tea.Cmd {
+	msg := pipelineRequestMsg{stage: m.stage, request: request}
+	return func() tea.Msg { return msg }
+}
+
+// Выполнение запроса этапа. Пока открыто окно заметки, запросы не принимаются.
+func (m model) handleRequest(msg pipelineRequestMsg) (tea.Model, tea.Cmd) {
+	if msg.stage != m.stage || m.stageNote.active {
+		return m, nil
+	}
+
+	switch msg.request {
+	case requestBack:
+		return m.returnToInfo(false)
+	case requestRestart:
+		return m.returnToInfo(true)
+	case requestRetry:
+		return m.retryStage()
+	}
+	// С экрана информации оператор продолжает прерванный этап
+	return m.resumeOrNext()
+}
+
+// Переход к следующему этапу после завершения текущего. О проваленном этапе
+// оператор оставляет заметку, перед записью отчета - общую заметку.
+func (m model) nextStage() (tea.Model, tea.Cmd) {
+	if m.stage+1 >= len(m.pipeline) {
+		return m, nil
+	}
+	m = m.finishStage()
+
+	advance := func(m model) (tea.Model, tea.Cmd) {
+		m.stage++
+		if _, ok := m.currentStage().(reportStage); ok {
+			return m.askStageNote(noteGeneral, model.startStage)
+		}
+		return m.startStage()
+	}
+
+	if status := m.currentStage().Result(m).Status; status == StageFail || status == StageError {
+		return m.askStageNote(noteFail, advance)
+	}
+	return advance(m)
+}
+
+// Запуск текущего этапа. Повторный запуск считается повтором этапа.
+func (m model) startStage() (tea.Model, tea.Cmd) {
+	times := slices.Clone(m.stageTimes)
+	timing := &times[m.stage]
+	retry := !timing.started.IsZero()
+	if retry {
+		timing.retries++
+	} else {
+		timing.started = time.Now()
+	}
+	timing.finished = time.Time{}
+	m.stageTimes = times
+
+	init := func(m model) (tea.Model, tea.Cmd) {
+		stage, cmd := m.currentStage().Init(m)
+		return m.setStage(stage), cmd
+	}
+	if retry {
+		return m.askStageNote(noteRetry, init)
+	}
+	return init(m)
+}
+
+// Отметка о завершении текущего этапа
+func (m model) finishStage() model {
+	times := slices.Clone(m.stageTimes)
+	if times[m.stage].finished.IsZero() {
+		times[m.stage].finished = time.Now()
+	}
+	m.stageTimes = times
+	return m
+}
+
+// Повтор проверки внутри этапа (повторный видеотест, повторный захват кадра):
+// оператор оставляет заметку, затем этап получает stageRetryMsg
+func (m model) retryStage() (tea.Model, tea.Cmd) {
+	times := slices.Clone(m.stageTimes)
+	times[m.stage].retries++
+	m.stageTimes = times
+	return m.askStageNote(noteRetry, func(m model) (tea.Model, tea.Cmd) {
+		return m.updateStage(stageRetryMsg{})
+	})
+}
+
+// Итоги всех этапов станции: этапы конвейера по порядку, затем не включенные в него
+func (m model) stageResults() []StageResult {
+	var results []StageResult
+	inPipeline := make(map[string]bool)
+
+	for i, stage := range m.pipeline {
+		inPipeline[stage.Name()] = true
+		// Вход оператора и отчет не входят в итоги, оператор пишется в отчет отдельно
+		switch stage.(type) {
+		case loginStage, reportStage:
+			continue
+		}
+
+		res := stage.Result(m)
+		res.Stage = stage.Name()
+		timing := m.stageTimes[i]
+		res.Started, res.Finished, res.Retries = timing.started, timing.finished, timing.retries
+		res.Notes = m.stageNotes[i]
+		if !timing.finished.IsZero() {
+			res.Duration = timing.finished.Sub(timing.started)
+		}
+		// Оператор ушел с этапа, не завершив его, или этап не запускался
+		if res.Status == "" && i != m.stage {
+			res.Status = StageAborted
+		}
+		results = append(results, res)
+	}
+
+	var skipped []string
+	for name := range stageCatalog {
+		if !inPipeline[name] {
+			skipped = append(skipped, name)
+		}
+	}
+	sort.Strings(skipped)
+	for _, name := range skipped {
+		results = append(results, StageResult{Stage: name, Status: StageSkip, Reasons: []string{"not in station pipeline"}})
+	}
+	return results
+}
+
+// Итоговый вердикт: все этапы пройдены или пропущены
+func unitPassed(results []StageResult) bool {
+	for _, res := range results {
+		if !res.ok() {
+			return false
+		}
+	}
+	return true
+}
+
+// Возврат к экрану системной информации. По ENTER оператор вернется
+// к прерванному этапу, при restart этап начнется заново.
+func (m model) returnToInfo(restart bool) (tea.Model, tea.Cmd) {
+	m.resumeStage = m.stage
+	m.resumeInit = restart
+	m.stage = infoStageIndex
+	return m, nil
+}
+
+// Продолжение с этапа, с которого оператор вернулся к информации, или следующий этап
+func (m model) resumeOrNext() (tea.Model, tea.Cmd) {
+	if m.resumeStage == 0 {
+		return m.nextStage()
+	}
+
+	m.stage, m.resumeStage = m.resumeStage, 0
+	if m.resumeInit {
+		return m.startStage()
+	}
+	return m, nil
+}
+
+// Оверлей по центру экрана
+func (m model) overlayView(content string) string {
+	overlay := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#222222")).
+		Padding(2, 4).
+		Align(lipgloss.Center).
+		Width(m.overlayWidth()).
+		Render(content)
+
+	// Над оверлеем строка со станцией, оператором и заказом, она видна на всех этапах
+	header := m.sessionHeader()
+	height := m.height
+	if header != "" {
+		height--
+	}
+	view := lipgloss.PlaceHorizontal(
+		m.width,
+		lipgloss.Center,
+		lipgloss.PlaceVertical(
+			height,
+			lipgloss.Center,
+			overlay,
+		),
+	)
+	if header != "" {
+		view = lipgloss.JoinVertical(lipgloss.Left, header, view)
+	}
+	return view
+}
+
+// Строка заголовка со станцией, оператором и заказом
+func (m model) sessionHeader() string {
+	line := m.sessionLine()
+	if line == "" {
+		return ""
+	}
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#CDCDCD")).
+		Width(m.width).
+		Align(lipgloss.Center).
+		Render(line)
+}
+
+// Ширина оверлея: половина экрана, на узких экранах почти весь экран
+func (m model) overlayWidth() int {
+	overlayWidth := m.width / 2
+	if overlayWidth < 50 {
+		overlayWidth = m.width - 10
+	}
+	return overlayWidth
+}
+
+// Экран системной информации, с которого начинается проверка
+type infoStage struct{}
+
+func (infoStage) Name() string { return "info" }
+
+func (s infoStage) Init(m model) (Stage, tea.Cmd) { return s, nil }
+
+func (s infoStage) Update(m model, msg tea.Msg) (Stage, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return s, tea.Quit
+		case "enter":
+			return s, m.request(requestNext)
+		}
+	}
+	return s, nil
+}
+
+func (infoStage) View(m model) string { return m.infoView() }
+
+// Проверки, которые выполняются при сборе информации: износ батареи и кодеки
+func (infoStage) Result(m model) StageResult {
+	var res StageResult
+	if m.collecting {
+		return res
+	}
+
+	for _, battery := range m.sysInfo.Power.Batteries {
+		res.value(battery.Name+" wear", "%.1f%%", battery.WearPercent)
+		res.value(battery.Name+" cycles", "%d", battery.CycleCount)
+		if battery.WearExceeded {
+			res.fail("battery wear: %s %.1f%%", battery.Name, battery.WearPercent)
+		}
+	}
+
+	var codecs []string
+	for _, card := range m.sysInfo.Audio.Cards {
+		for _, codec := range card.Codecs {
+			codecs = append(codecs, codec.Name)
+		}
+	}
+	if len(codecs) > 0 {
+		res.value("audio codecs", "%s", strings.Join(codecs, ", "))
+	}
+	if missing := m.sysInfo.Audio.MissingCodecs; len(missing) > 0 {
+		res.fail("audio codec missing: %s", strings.Join(missing, ", "))
+	}
+
+	// Каждое отклонение от комплектации - отдельная причина провала
+	if name, deviations, ok := m.bomCheck(); ok {
+		res.value("bom spec", "%s", name)
+		for _, deviation := range deviations {
+			res.fail("bom %s", deviation)
+		}
+	}
+
+	// Заглушка в DMI не бракует устройство, но отмечается в отчете
+	if m.dmiSerialPlaceholder() {
+		res.value("dmi serial placeholder", "%q", m.sysInfo.SerialNumber)
+	}
+
+	// Критический порог бракует устройство только по настройке станции
+	if warning := m.sysInfo.Sensors.Warning; warning != "" {
+		res.value("sensors read error", "%s", warning)
+	}
+	if critical := m.sysInfo.Sensors.criticalSensors(); len(critical) > 0 {
+		if m.config.Sensors.FailOnCritical {
+			res.fail("sensors over critical: %s", strings.Join(critical, ", "))
+		} else {
+			res.value("sensors over critical", "%s", strings.Join(critical, ", "))
+		}
+	}
+
+	res.settle()
+	return res
+}
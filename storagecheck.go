@@ -0,0 +1,477 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Выравнивание буфера и смещений для чтения с O_DIRECT
+const directIOAlign = 4096
+
+// Сколько байт читается за один шаг, чтобы интерфейс успевал обновляться
+const diskReadStepBytes = 64 << 20
+
+// Результат проверки чтения одного накопителя
+type DiskReadResult struct {
+	Device        string
+	Model         string
+	SizeBytes     int64
+	BytesRead     int64
+	Duration      time.Duration
+	ThroughputMBs float64
+	LatencyP50    time.Duration
+	LatencyP95    time.Duration
+	LatencyP99    time.Duration
+	ReadErrors    []string // Смещения и причины ошибок чтения
+	Skipped       string   // Причина пропуска устройства
+	Passed        bool
+}
+
+// Участок диска для чтения
+type diskRegion struct {
+	offset int64
+	length int64
+}
+
+// Состояние чтения одного накопителя между шагами
+type diskReader struct {
+	result    DiskReadResult
+	file      *os.File
+	buf       []byte
+	regions   []diskRegion
+	region    int
+	pos       int64 // Смещение внутри текущего участка
+	total     int64 // Сколько байт нужно прочитать всего
+	latencies []time.Duration
+	minSpeed  float64
+}
+
+// Выделение буфера, выровненного для O_DIRECT
+func alignedBuffer(size int) []byte {
+	raw := make([]byte, size+directIOAlign)
+	shift := 0
+	if rem := int(uintptr(unsafe.Pointer(&raw[0])) & (directIOAlign - 1)); rem != 0 {
+		shift = directIOAlign - rem
+	}
+	return raw[shift : shift+size]
+}
+
+// blockDeviceBusy проверяет, смонтирован ли диск, его разделы или используется ли он
+// device-mapper/md. Возвращает причину занятости или пустую строку.
+func blockDeviceBusy(name string) string {
+	sysDir := filepath.Join("/sys/class/block", name)
+
+	mounts, err := os.Open("/proc/mounts")
+	if err == nil {
+		defer mounts.Close()
+		scanner := bufio.NewScanner(mounts)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 || !strings.HasPrefix(fields[0], "/dev/") {
+				continue
+			}
+			source := fields[0]
+			if resolved, err := filepath.EvalSymlinks(source); err == nil {
+				source = resolved
+			}
+			base := filepath.Base(source)
+			if base == name {
+				return fmt.Sprintf("mounted at %s", fields[1])
+			}
+			if _, err := os.Stat(filepath.Join(sysDir, base)); err == nil {
+				return fmt.Sprintf("partition %s mounted at %s", base, fields[1])
+			}
+		}
+	}
+
+	// Диск или его разделы входят в LVM, RAID или шифрованный том
+	holderDirs, _ := filepath.Glob(filepath.Join(sysDir, "*", "holders"))
+	holderDirs = append(holderDirs, filepath.Join(sysDir, "holders"))
+	for _, dir := range holderDirs {
+		if holders, err := os.ReadDir(dir); err == nil && len(holders) > 0 {
+			return fmt.Sprintf("in use by %s", holders[0].Name())
+		}
+	}
+
+	return ""
+}
+
+// Ошибка открытия занятого устройства, такое устройство пропускается, а не проваливается
+type deviceBusyError struct {
+	device string
+	reason string
+}
+
+func (e deviceBusyError) Error() string {
+	return fmt.Sprintf("устройство %s используется (%s), проверка запрещена", e.device, e.reason)
+}
+
+// Подготовка чтения накопителя: проверка занятости, открытие с O_DIRECT и разметка участков
+func openDiskReader(device, model string, cfg StorageTestConfig) (*diskReader, error) {
+	r := &diskReader{
+		result:   DiskReadResult{Device: device, Model: model},
+		minSpeed: cfg.MinThroughputMBs,
+	}
+
+	name := filepath.Base(device)
+	if resolved, err := filepath.EvalSymlinks(device); err == nil {
+		name = filepath.Base(resolved)
+	}
+	if reason := blockDeviceBusy(name); reason != "" {
+		return nil, deviceBusyError{device: device, reason: reason}
+	}
+
+	// O_EXCL на блочном устройстве не даст открыть смонтированный диск
+	file, err := os.OpenFile(device, os.O_RDONLY|syscall.O_DIRECT|syscall.O_EXCL, 0)
+	if errors.Is(err, syscall.EBUSY) {
+		return nil, deviceBusyError{device: device, reason: "opened exclusively by kernel"}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть %s: %v", device, err)
+	}
+
+	size, err := file.Seek(0, 2)
+	if err != nil || size <= 0 {
+		file.Close()
+		return nil, fmt.Errorf("не удалось определить размер %s: %v", device, err)
+	}
+
+	r.file = file
+	r.result.SizeBytes = size
+	r.buf = alignedBuffer(cfg.BlockSizeKB << 10)
+	r.regions = planDiskRegions(size, cfg)
+	for _, region := range r.regions {
+		r.total += region.length
+	}
+
+	return r, nil
+}
+
+// Разметка участков чтения: весь диск или равномерно распределенные участки
+func planDiskRegions(size int64, cfg StorageTestConfig) []diskRegion {
+	size -= size % directIOAlign
+	regionSize := int64(cfg.RegionSizeMB) << 20
+
+	if cfg.Mode == "full" || int64(cfg.SampleRegions)*regionSize >= size {
+		return []diskRegion{{offset: 0, length: size}}
+	}
+
+	regions := make([]diskRegion, 0, cfg.SampleRegions)
+	if cfg.SampleRegions == 1 {
+		return append(regions, diskRegion{offset: 0, length: regionSize})
+	}
+
+	// Первый участок в начале диска, последний - в конце
+	stride := (size - regionSize) / int64(cfg.SampleRegions-1)
+	for i := 0; i < cfg.SampleRegions; i++ {
+		offset := int64(i) * stride
+		offset -= offset % directIOAlign
+		regions = append(regions, diskRegion{offset: offset, length: regionSize})
+	}
+	return regions
+}
+
+// Чтение очередной порции данных. Возвращает true, когда все участки прочитаны.
+func (r *diskReader) step() bool {
+	deadline := r.result.BytesRead + diskReadStepBytes
+	started := time.Now()
+
+	for r.region < len(r.regions) && r.result.BytesRead < deadline {
+		region := r.regions[r.region]
+		chunk := int64(len(r.buf))
+		if remaining := region.length - r.pos; remaining < chunk {
+			chunk = remaining
+		}
+
+		offset := region.offset + r.pos
+		readStart := time.Now()
+		n, err := r.file.ReadAt(r.buf[:chunk], offset)
+		r.latencies = append(r.latencies, time.Since(readStart))
+
+		// Короткое чтение без ошибки - тоже сбой: блок прочитан не полностью
+		if err == nil && int64(n) < chunk {
+			err = fmt.Errorf("short read: %d of %d bytes", n, chunk)
+		}
+		if err != nil {
+			r.result.ReadErrors = append(r.result.ReadErrors,
+				fmt.Sprintf("offset %d: %v", offset, err))
+		}
+
+		// Нечитаемый блок пропускаем и продолжаем со следующего
+		r.result.BytesRead += chunk
+		r.pos += chunk
+		if r.pos >= region.length {
+			r.region++
+			r.pos = 0
+		}
+	}
+
+	r.result.Duration += time.Since(started)
+	return r.region >= len(r.regions)
+}
+
+// Доля прочитанных данных
+func (r *diskReader) progress() float64 {
+	if r.total == 0 {
+		return 1
+	}
+	return float64(r.result.BytesRead) / float64(r.total)
+}
+
+// Завершение чтения и расчет итоговых показателей
+func (r *diskReader) finish() DiskReadResult {
+	r.file.Close()
+
+	res := r.result
+	if res.Duration > 0 {
+		res.ThroughputMBs = float64(res.BytesRead) / (1 << 20) / res.Duration.Seconds()
+	}
+
+	sorted := append([]time.Duration(nil), r.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	res.LatencyP50 = latencyPercentile(sorted, 0.50)
+	res.LatencyP95 = latencyPercentile(sorted, 0.95)
+	res.LatencyP99 = latencyPercentile(sorted, 0.99)
+
+	res.Passed = len(res.ReadErrors) == 0 && (r.minSpeed <= 0 || res.ThroughputMBs >= r.minSpeed)
+	return res
+}
+
+// Перцентиль по отсортированному списку задержек методом ближайшего ранга:
+// наименьший замер, который не меньше доли q всех замеров
+func latencyPercentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
+
+// Список устройств для проверки: из конфигурации или все несистемные диски
+func storageTestTargets(cfg StorageTestConfig, storage []StorageInfo) ([]DiskReadResult, []DiskReadResult) {
+	var targets, skipped []DiskReadResult
+
+	if len(cfg.Devices) > 0 {
+		for _, device := range cfg.Devices {
+			targets = append(targets, DiskReadResult{Device: device})
+		}
+		return targets, skipped
+	}
+
+	for _, disk := range storage {
+		res := DiskReadResult{Device: "/dev/" + disk.Name, Model: disk.Model}
+		switch {
+		case disk.DeviceType == "rom":
+			res.Skipped = "optical drive"
+		case disk.Removable:
+			// Флешки и кардридеры не входят в комплектацию, пустой слот не читается
+			res.Skipped = "removable media"
+		case len(disk.Mountpoints) > 0:
+			res.Skipped = "system disk, mounted at " + strings.Join(disk.Mountpoints, ", ")
+		}
+
+		if res.Skipped != "" {
+			skipped = append(skipped, res)
+		} else {
+			targets = append(targets, res)
+		}
+	}
+	return targets, skipped
+}
+
+// Этап проверки чтения накопителей
+type storageStage struct {
+	queue    []DiskReadResult
+	current  int
+	reader   *diskReader
+	progress float64
+	results  []DiskReadResult
+	done     bool
+}
+
+// Прогресс чтения текущего диска
+type storageTestStepMsg struct {
+	reader   *diskReader
+	progress float64
+}
+
+// Диск проверен или пропущен
+type storageTestDiskDoneMsg struct {
+	result DiskReadResult
+}
+
+// Открытие очередного диска из очереди
+func openNextDiskCmd(target DiskReadResult, cfg StorageTestConfig) tea.Cmd {
+	return func() tea.Msg {
+		reader, err := openDiskReader(target.Device, target.Model, cfg)
+		var busy deviceBusyError
+		if errors.As(err, &busy) {
+			target.Skipped = busy.Error()
+			return storageTestDiskDoneMsg{result: target}
+		}
+		if err != nil {
+			target.ReadErrors = append(target.ReadErrors, err.Error())
+			return storageTestDiskDoneMsg{result: target}
+		}
+		return storageTestStepMsg{reader: reader}
+	}
+}
+
+// Чтение следующей порции текущего диска
+func readDiskStepCmd(reader *diskReader) tea.Cmd {
+	return func() tea.Msg {
+		if reader.step() {
+			return storageTestDiskDoneMsg{result: reader.finish()}
+		}
+		return storageTestStepMsg{reader: reader, progress: reader.progress()}
+	}
+}
+
+// Итог проверки накопителей: провал, если хотя бы один диск не прошел
+func (s storageStage) passed() bool {
+	for _, res := range s.results {
+		if res.Skipped == "" && !res.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+func (storageStage) Name() string { return "storage" }
+
+// Запуск этапа проверки накопителей
+func (storageStage) Init(m model) (Stage, tea.Cmd) {
+	queue, skipped := storageTestTargets(m.config.StorageTest, m.sysInfo.Storage)
+
+	s := storageStage{queue: queue, results: skipped}
+	if len(queue) == 0 {
+		s.done = true
+		return s, nil
+	}
+	return s, openNextDiskCmd(queue[0], m.config.StorageTest)
+}
+
+func (s storageStage) Update(m model, msg tea.Msg) (Stage, tea.Cmd) {
+	switch msg := msg.(type) {
+	case storageTestStepMsg:
+		s.reader = msg.reader
+		s.progress = msg.progress
+		return s, readDiskStepCmd(msg.reader)
+
+	case storageTestDiskDoneMsg:
+		s.reader = nil
+		s.progress = 0
+		s.results = append(s.results, msg.result)
+		s.current++
+		if s.current < len(s.queue) {
+			return s, openNextDiskCmd(s.queue[s.current], m.config.StorageTest)
+		}
+		s.done = true
+		return s, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return s, tea.Quit
+		}
+
+		// Во время чтения дисков выход запрещен, чтобы не бросить открытое устройство
+		if !s.done {
+			return s, nil
+		}
+
+		switch msg.String() {
+		case "enter":
+			return s, m.request(requestNext)
+		case "b":
+			return s, m.request(requestBack)
+		}
+	}
+	return s, nil
+}
+
+// Содержимое оверлея проверки накопителей
+func (s storageStage) content() string {
+	var content strings.Builder
+
+	for _, res := range s.results {
+		switch {
+		case res.Skipped != "":
+			content.WriteString(fmt.Sprintf("- %s: skipped (%s)\n", res.Device, res.Skipped))
+		case res.Passed:
+			content.WriteString(fmt.Sprintf("✓ %s: %.1f MB/s, p99 %s\n", res.Device, res.ThroughputMBs, res.LatencyP99.Round(time.Microsecond)))
+		default:
+			content.WriteString(fmt.Sprintf("✗ %s: %.1f MB/s, %d read errors\n", res.Device, res.ThroughputMBs, len(res.ReadErrors)))
+		}
+	}
+
+	if !s.done && s.current < len(s.queue) {
+		barWidth := 30
+		filled := int(s.progress * float64(barWidth))
+		content.WriteString(fmt.Sprintf("\nReading %s\n[%s%s] %3.0f%%\n",
+			s.queue[s.current].Device,
+			strings.Repeat("█", filled),
+			strings.Repeat("░", barWidth-filled),
+			s.progress*100,
+		))
+	}
+
+	footer := "Reading disks, please wait..."
+	if s.done {
+		verdict := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#00AA00")).Render("Storage read test PASSED")
+		if !s.passed() {
+			verdict = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FF5555")).Render("Storage read test FAILED")
+		}
+		footer = verdict + "\n\n[ENTER] Continue   [B] Return to system information"
+	}
+
+	return fmt.Sprintf(
+		"%s\n\n%s\n%s",
+		lipgloss.NewStyle().Bold(true).Render("Storage Read Verification"),
+		content.String(),
+		footer,
+	)
+}
+
+func (s storageStage) View(m model) string { return m.overlayView(s.content()) }
+
+func (s storageStage) Result(m model) StageResult {
+	var res StageResult
+	if !s.done {
+		return res
+	}
+
+	tested := 0
+	for _, disk := range s.results {
+		if disk.Skipped != "" {
+			res.value(disk.Device, "skipped: %s", disk.Skipped)
+			continue
+		}
+		tested++
+		res.value(disk.Device, "%.1f MB/s, p99 %s, %d read errors",
+			disk.ThroughputMBs, disk.LatencyP99.Round(time.Microsecond), len(disk.ReadErrors))
+		if !disk.Passed {
+			res.fail("storage read test: %s", disk.Device)
+		}
+	}
+
+	if tested == 0 {
+		res.Status = StageSkip
+		res.Reasons = []string{"no disks to test"}
+		return res
+	}
+	res.settle()
+	return res
+}
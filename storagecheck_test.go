@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlanDiskRegions(t *testing.T) {
+	const mb = 1 << 20
+
+	tests := []struct {
+		name string
+		size int64
+		cfg  StorageTestConfig
+		want []diskRegion
+	}{
+		{
+			name: "full mode reads whole disk",
+			size: 10*mb + 100,
+			cfg:  StorageTestConfig{Mode: "full", SampleRegions: 4, RegionSizeMB: 1},
+			want: []diskRegion{{offset: 0, length: 10 * mb}},
+		},
+		{
+			name: "samples cover whole disk",
+			size: 3 * mb,
+			cfg:  StorageTestConfig{Mode: "sample", SampleRegions: 4, RegionSizeMB: 1},
+			want: []diskRegion{{offset: 0, length: 3 * mb}},
+		},
+		{
+			name: "single sample at start",
+			size: 100 * mb,
+			cfg:  StorageTestConfig{Mode: "sample", SampleRegions: 1, RegionSizeMB: 1},
+			want: []diskRegion{{offset: 0, length: mb}},
+		},
+		{
+			name: "first and last samples at disk edges",
+			size: 100 * mb,
+			cfg:  StorageTestConfig{Mode: "sample", SampleRegions: 3, RegionSizeMB: 1},
+			want: []diskRegion{
+				{offset: 0, length: mb},
+				{offset: 51904512, length: mb},
+				{offset: 99 * mb, length: mb},
+			},
+		},
+		{
+			name: "unaligned tail is not read",
+			size: 100*mb + 1000,
+			cfg:  StorageTestConfig{Mode: "sample", SampleRegions: 3, RegionSizeMB: 1},
+			want: []diskRegion{
+				{offset: 0, length: mb},
+				{offset: 51904512, length: mb},
+				{offset: 99 * mb, length: mb},
+			},
+		},
+		{
+			name: "offsets aligned for O_DIRECT",
+			size: 7*mb + directIOAlign,
+			cfg:  StorageTestConfig{Mode: "sample", SampleRegions: 4, RegionSizeMB: 1},
+			want: []diskRegion{
+				{offset: 0, length: mb},
+				{offset: 2097152, length: mb},
+				{offset: 4194304, length: mb},
+				{offset: 6291456, length: mb},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planDiskRegions(tt.size, tt.cfg)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("planDiskRegions(%d) = %v, want %v", tt.size, got, tt.want)
+			}
+			for _, r := range got {
+				if r.offset%directIOAlign != 0 || r.offset+r.length > tt.size {
+					t.Errorf("region %+v is unaligned or past disk end %d", r, tt.size)
+				}
+			}
+		})
+	}
+}
+
+func TestLatencyPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 10; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		q      float64
+		want   time.Duration
+	}{
+		{"empty", nil, 0.5, 0},
+		{"single", []time.Duration{3 * time.Millisecond}, 0.99, 3 * time.Millisecond},
+		{"min", sorted, 0, 1 * time.Millisecond},
+		{"median", sorted, 0.5, 5 * time.Millisecond},
+		{"p90", sorted, 0.9, 9 * time.Millisecond},
+		{"p99", sorted, 0.99, 10 * time.Millisecond},
+		{"max", sorted, 1, 10 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := latencyPercentile(tt.sorted, tt.q); got != tt.want {
+				t.Errorf("latencyPercentile(q=%v) = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
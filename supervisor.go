@@ -0,0 +1,311 @@
+package main
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Попытка разблокировки сверки серийного номера супервизором
+type SupervisorOverride struct {
+	Time     time.Time `json:"time"`
+	Failures int       `json:"failures"` // Несовпадений до блокировки
+	Granted  bool      `json:"granted"`
+}
+
+func newSupervisorInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "PIN"
+	ti.EchoMode = textinput.EchoPassword
+	ti.CharLimit = 64
+	ti.Width = 20
+	return ti
+}
+
+// Хеш пароля супервизора: pbkdf2-sha256$<итерации>$<соль>$<ключ>, соль и
+// ключ в base64 без выравнивания
+const (
+	supervisorHashScheme     = "pbkdf2-sha256"
+	supervisorHashIterations = 600000 // Рекомендация OWASP для PBKDF2-HMAC-SHA256
+	supervisorHashSaltSize   = 16
+	supervisorHashKeySize    = 32
+)
+
+// После неверного PIN следующая попытка возможна не сразу: задержка удваивается
+// с каждой ошибкой подряд и упирается в блокировку на supervisorPINLockout
+const (
+	supervisorPINDelay   = 2 * time.Second
+	supervisorPINLockout = 15 * time.Minute
+)
+
+// Разобранный хеш пароля супервизора
+type supervisorHash struct {
+	iterations int
+	salt       []byte
+	key        []byte
+}
+
+func parseSupervisorHash(encoded string) (supervisorHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != supervisorHashScheme {
+		return supervisorHash{}, fmt.Errorf("ожидается %s$<итерации>$<соль>$<ключ>", supervisorHashScheme)
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return supervisorHash{}, fmt.Errorf("некорректное число итераций %q", parts[1])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil || len(salt) == 0 {
+		return supervisorHash{}, fmt.Errorf("некорректная соль")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return supervisorHash{}, fmt.Errorf("некорректный ключ")
+	}
+	return supervisorHash{iterations: iterations, salt: salt, key: key}, nil
+}
+
+// Хеш пароля со случайной солью для supervisor_password_hash
+func hashSupervisorPassword(password string) (string, error) {
+	salt := make([]byte, supervisorHashSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, supervisorHashIterations, supervisorHashKeySize)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s$%d$%s$%s", supervisorHashScheme, supervisorHashIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h supervisorHash) check(password string) bool {
+	key, err := pbkdf2.Key(sha256.New, password, h.salt, h.iterations, len(h.key))
+	return err == nil && subtle.ConstantTimeCompare(key, h.key) == 1
+}
+
+// Разблокировка доступна, если в конфигурации задан PIN или хеш пароля
+func (c SerialConfig) supervisorEnabled() bool {
+	return c.SupervisorPIN != "" || c.SupervisorPasswordHash != ""
+}
+
+// Проверка PIN супервизора. Хеш проверен при загрузке конфигурации.
+func (c SerialConfig) checkSupervisorPIN(pin string) bool {
+	if c.SupervisorPasswordHash != "" {
+		hash, err := parseSupervisorHash(c.SupervisorPasswordHash)
+		return err == nil && hash.check(pin)
+	}
+	return c.SupervisorPIN != "" && subtle.ConstantTimeCompare([]byte(pin), []byte(c.SupervisorPIN)) == 1
+}
+
+// Подпись поля ввода: PIN или пароль, если задан хеш пароля
+func (c SerialConfig) supervisorSecretName() string {
+	if c.SupervisorPasswordHash != "" {
+		return "Supervisor password"
+	}
+	return "Supervisor PIN"
+}
+
+// Задержка перед следующей попыткой после denials неверных PIN подряд
+func supervisorRetryDelay(denials int) time.Duration {
+	if denials <= 0 {
+		return 0
+	}
+	delay := supervisorPINDelay
+	for i := 1; i < denials && delay < supervisorPINLockout; i++ {
+		delay *= 2
+	}
+	return min(delay, supervisorPINLockout)
+}
+
+// Результат проверки PIN. PBKDF2 считается заметное время, поэтому проверка
+// идет в команде, а не в Update.
+type supervisorCheckedMsg struct {
+	granted bool
+}
+
+func checkSupervisorPINCmd(cfg SerialConfig, pin string) tea.Cmd {
+	return func() tea.Msg {
+		return supervisorCheckedMsg{granted: cfg.checkSupervisorPIN(pin)}
+	}
+}
+
+// Сверка заблокирована после слишком многих несовпадений
+func (s serialStage) locked(cfg SerialConfig) bool {
+	return cfg.MaxAttempts > 0 && s.failures >= cfg.MaxAttempts
+}
+
+// Строки журнала попыток, по которым восстанавливается блокировка
+var (
+	serialAttemptLine  = regexp.MustCompile(`system=("(?:[^"\\]|\\.)*") entered=(?:"(?:[^"\\]|\\.)*") raw=(?:"(?:[^"\\]|\\.)*") (match|mismatch)`)
+	serialOverrideLine = regexp.MustCompile(`system=("(?:[^"\\]|\\.)*") override granted=(true|false)`)
+)
+
+// Несовпадения номера устройства с последнего совпадения или разблокировки
+// по журналу попыток. Так блокировка переживает перезапуск программы.
+func persistedSerialFailures(system string) int {
+	data, err := os.ReadFile(serialAttemptsLogPath())
+	if err != nil {
+		return 0
+	}
+
+	failures := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		if match := serialAttemptLine.FindStringSubmatch(line); match != nil {
+			if s, err := strconv.Unquote(match[1]); err != nil || s != system {
+				continue
+			}
+			if match[2] == "match" {
+				failures = 0
+			} else {
+				failures++
+			}
+		} else if match := serialOverrideLine.FindStringSubmatch(line); match != nil {
+			if s, err := strconv.Unquote(match[1]); err == nil && s == system && match[2] == "true" {
+				failures = 0
+			}
+		}
+	}
+	return failures
+}
+
+// Неверные PIN подряд с последней разблокировки и время последнего из них по
+// журналу попыток: задержка перед следующей попыткой переживает перезапуск
+func persistedSupervisorDenials(system string) (int, time.Time) {
+	data, err := os.ReadFile(serialAttemptsLogPath())
+	if err != nil {
+		return 0, time.Time{}
+	}
+
+	denials := 0
+	var last time.Time
+	for _, line := range strings.Split(string(data), "\n") {
+		match := serialOverrideLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if s, err := strconv.Unquote(match[1]); err != nil || s != system {
+			continue
+		}
+		if match[2] == "true" {
+			denials = 0
+			continue
+		}
+		denials++
+		stamp, _, _ := strings.Cut(line, " ")
+		if t, err := time.Parse(time.RFC3339, stamp); err == nil {
+			last = t
+		}
+	}
+	return denials, last
+}
+
+// Несовпадения с учетом прошлых запусков. Заглушка в DMI общая у многих
+// устройств, поэтому для нее блокировка не восстанавливается.
+func (s serialStage) restoreFailures(m model) int {
+	if m.dmiSerialPlaceholder() {
+		return s.failures
+	}
+	return max(s.failures, persistedSerialFailures(m.sysInfo.SerialNumber))
+}
+
+// Задержка после неверных PIN с учетом прошлых запусков, для заглушки в DMI -
+// только текущего
+func (s serialStage) restorePINDenials(m model) serialStage {
+	if m.dmiSerialPlaceholder() {
+		return s
+	}
+	denials, last := persistedSupervisorDenials(m.sysInfo.SerialNumber)
+	if denials > s.pinDenials {
+		s.pinDenials = denials
+		s.pinRetryAt = last.Add(supervisorRetryDelay(denials))
+	}
+	return s
+}
+
+// Попытка разблокировки пишется в журнал попыток: успешная снимает блокировку
+// и после перезапуска
+func logSupervisorOverrideCmd(session operatorSession, system string, override SupervisorOverride) tea.Cmd {
+	return func() tea.Msg {
+		err := appendSerialLog(fmt.Sprintf("%s station=%q operator=%q system=%q override granted=%t failures=%d",
+			override.Time.Format(time.RFC3339), session.Station, session.Operator, system, override.Granted, override.Failures))
+		if err != nil {
+			return serialLogFailedMsg{err}
+		}
+		return nil
+	}
+}
+
+// Ввод PIN супервизора на заблокированном этапе сверки
+func (s serialStage) updateSupervisor(m model, msg tea.KeyMsg) (Stage, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return s, tea.Quit
+	}
+
+	// Пока PIN проверяется, ввод не принимается
+	if s.pinChecking {
+		return s, nil
+	}
+
+	switch msg.String() {
+	case "esc":
+		s.phase = serialPhaseLocked
+		s.supervisorInput.Blur()
+		return s, nil
+
+	case "enter":
+		if wait := time.Until(s.pinRetryAt); wait > 0 {
+			s.supervisorError = fmt.Sprintf("Too many wrong PINs, try again in %s", wait.Round(time.Second))
+			return s, nil
+		}
+		s.pinChecking = true
+		s.supervisorError = "Checking..."
+		pin := s.supervisorInput.Value()
+		s.supervisorInput.SetValue("")
+		return s, checkSupervisorPINCmd(m.config.Serial, pin)
+	}
+
+	var cmd tea.Cmd
+	s.supervisorInput, cmd = s.supervisorInput.Update(msg)
+	return s, cmd
+}
+
+// Итог проверки PIN супервизора
+func (s serialStage) applySupervisorCheck(m model, granted bool) (Stage, tea.Cmd) {
+	s.pinChecking = false
+	override := SupervisorOverride{
+		Time:     time.Now(),
+		Failures: s.failures,
+		Granted:  granted,
+	}
+	s.overrides = append(slices.Clone(s.overrides), override)
+	logCmd := logSupervisorOverrideCmd(m.session, m.sysInfo.SerialNumber, override)
+	if !granted {
+		s.pinDenials++
+		s.pinRetryAt = override.Time.Add(supervisorRetryDelay(s.pinDenials))
+		s.supervisorError = fmt.Sprintf("Wrong PIN, next attempt in %s", supervisorRetryDelay(s.pinDenials))
+		return s, logCmd
+	}
+
+	// Разблокировка: счетчик несовпадений начинается заново, после
+	// заметки оператора номер вводится снова
+	s.supervisorError = ""
+	s.supervisorInput.Blur()
+	s.failures = 0
+	s.pinDenials = 0
+	s.pinRetryAt = time.Time{}
+	return s, tea.Batch(m.request(requestRetry), logCmd)
+}
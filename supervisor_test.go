@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSupervisorPasswordHash(t *testing.T) {
+	encoded, err := hashSupervisorPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("hashSupervisorPassword: %v", err)
+	}
+	if _, err := parseSupervisorHash(encoded); err != nil {
+		t.Fatalf("parseSupervisorHash(%q): %v", encoded, err)
+	}
+
+	cfg := SerialConfig{SupervisorPasswordHash: encoded}
+	if !cfg.supervisorEnabled() {
+		t.Error("supervisorEnabled() = false with a password hash")
+	}
+	if !cfg.checkSupervisorPIN("correct horse battery staple") {
+		t.Error("checkSupervisorPIN rejected the right password")
+	}
+	if cfg.checkSupervisorPIN("correct horse battery") {
+		t.Error("checkSupervisorPIN accepted a wrong password")
+	}
+
+	// Соль случайная: у одного пароля разные хеши
+	again, err := hashSupervisorPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("hashSupervisorPassword: %v", err)
+	}
+	if again == encoded {
+		t.Error("two hashes of the same password are equal, salt is not random")
+	}
+}
+
+func TestParseSupervisorHashErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"plain pin", "1234"},
+		{"unknown scheme", "sha256$1000$c2FsdA$a2V5"},
+		{"missing key", "pbkdf2-sha256$1000$c2FsdA"},
+		{"zero iterations", "pbkdf2-sha256$0$c2FsdA$a2V5"},
+		{"bad salt", "pbkdf2-sha256$1000$!!$a2V5"},
+		{"empty key", "pbkdf2-sha256$1000$c2FsdA$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseSupervisorHash(tt.encoded); err == nil {
+				t.Errorf("parseSupervisorHash(%q) succeeded, want error", tt.encoded)
+			}
+		})
+	}
+}
+
+func TestSupervisorRetryDelay(t *testing.T) {
+	tests := []struct {
+		denials int
+		want    time.Duration
+	}{
+		{0, 0},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{5, 32 * time.Second},
+		{9, 512 * time.Second},
+		{10, supervisorPINLockout},
+		{1000, supervisorPINLockout},
+	}
+
+	for _, tt := range tests {
+		if got := supervisorRetryDelay(tt.denials); got != tt.want {
+			t.Errorf("supervisorRetryDelay(%d) = %s, want %s", tt.denials, got, tt.want)
+		}
+	}
+}
+
+func TestPersistedSupervisorDenials(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	log := `2026-10-18T10:00:00Z station="st1" operator="op1" system="SN1" override granted=false failures=3
+2026-10-18T10:01:00Z station="st1" operator="op1" system="SN1" override granted=true failures=3
+2026-10-18T10:02:00Z station="st1" operator="op1" system="SN1" override granted=false failures=3
+2026-10-18T10:03:00Z station="st1" operator="op1" system="SN2" override granted=false failures=3
+2026-10-18T10:04:00Z station="st1" operator="op1" system="SN1" override granted=false failures=3
+`
+	if err := os.WriteFile(filepath.Join(logsDir, "serial_attempts.log"), []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Считаются только отказы после последней разблокировки этого устройства
+	denials, last := persistedSupervisorDenials("SN1")
+	if denials != 2 {
+		t.Errorf("denials = %d, want 2", denials)
+	}
+	if want := time.Date(2026, 10, 18, 10, 4, 0, 0, time.UTC); !last.Equal(want) {
+		t.Errorf("last denial = %s, want %s", last, want)
+	}
+
+	if denials, _ := persistedSupervisorDenials("SN3"); denials != 0 {
+		t.Errorf("denials for an unknown unit = %d, want 0", denials)
+	}
+}
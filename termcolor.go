@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Цветовые возможности терминала, на котором показывается видеотест
+type TerminalColors struct {
+	Profile   string // truecolor, 256 colors, 16 colors, no color
+	Term      string // Значение TERM
+	ColorTerm string // Значение COLORTERM
+	TrueColor bool
+}
+
+// Определение цветового профиля терминала. На консоли Linux (TERM=linux)
+// цвета #RRGGBB сводятся к 16 цветам, и шаблоны видеотеста искажаются.
+func detectTerminalColors() TerminalColors {
+	profile := lipgloss.ColorProfile()
+
+	colors := TerminalColors{
+		Term:      os.Getenv("TERM"),
+		ColorTerm: os.Getenv("COLORTERM"),
+		TrueColor: profile == termenv.TrueColor,
+	}
+	switch profile {
+	case termenv.TrueColor:
+		colors.Profile = "truecolor"
+	case termenv.ANSI256:
+		colors.Profile = "256 colors"
+	case termenv.ANSI:
+		colors.Profile = "16 colors"
+	default:
+		colors.Profile = "no color"
+	}
+	return colors
+}
+
+// Описание профиля для отчета, например "16 colors (TERM=linux)"
+func (t TerminalColors) String() string {
+	text := t.Profile + " (TERM=" + t.Term
+	if t.ColorTerm != "" {
+		text += ", COLORTERM=" + t.ColorTerm
+	}
+	return text + ")"
+}
+
+// Предупреждение или отказ перед видеотестом без поддержки true color
+func (m model) colorCheckView() string {
+	warning := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FF5555"))
+
+	text := "This terminal does not support true color.\n" +
+		"Test patterns will be shown with quantized colors,\n" +
+		"good panels may look defective."
+
+	options := "[ENTER] Run video test anyway   [Q] Quit"
+	if m.config.VideoTest.ColorPolicy == "refuse" {
+		options = "Video test is not allowed on this terminal.\n" +
+			"Run the tool in a true color terminal (e.g. kmscon, foot, xterm-direct).\n\n" +
+			"[ENTER] Continue, video test is recorded as failed   [Q] Quit"
+	}
+
+	return warning.Render("Terminal Color Check") + "\n\n" +
+		"Detected: " + m.terminalColors.String() + "\n\n" +
+		text + "\n\n" +
+		options
+}
@@ -0,0 +1,539 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Период таймера видеотеста
+const videoTestTickInterval = 250 * time.Millisecond
+
+// Фаза видеотеста
+type videoPhase int
+
+const (
+	videoPhaseColorCheck videoPhase = iota // Предупреждение о терминале без true color
+	videoPhasePatterns                     // Показ шаблонов на весь экран
+	videoPhaseSummary                      // Итоги и решение оператора
+)
+
+// Тестовый шаблон видеотеста
+type videoPattern struct {
+	name     string
+	duration time.Duration // 0 - шаблон показывается до нажатия клавиши
+	render   func(width, height int) string
+}
+
+// Вердикт оператора по одному шаблону
+type VideoPatternResult struct {
+	Name   string
+	Shown  bool
+	Failed bool
+	Reason string
+}
+
+// Результат видеотеста по всем шаблонам
+type VideoTestResult struct {
+	Patterns []VideoPatternResult
+	Terminal TerminalColors // Профиль терминала, на котором показывались шаблоны
+}
+
+// Видеотест пройден, если все шаблоны показаны и ни один не отмечен как сбойный
+func (v VideoTestResult) Passed() bool {
+	if len(v.Patterns) == 0 {
+		return false
+	}
+	for _, pattern := range v.Patterns {
+		if !pattern.Shown || pattern.Failed {
+			return false
+		}
+	}
+	return true
+}
+
+// Названия сбойных шаблонов с причинами
+func (v VideoTestResult) failedPatterns() []string {
+	var failed []string
+	for _, pattern := range v.Patterns {
+		if pattern.Failed {
+			failed = append(failed, fmt.Sprintf("%s (%s)", pattern.Name, pattern.Reason))
+		}
+	}
+	return failed
+}
+
+// Последовательность шаблонов по умолчанию, если в конфигурации она не задана
+var defaultVideoSequence = []VideoPatternConfig{
+	{Pattern: "solid", Name: "RED", Color: "#FF0000", Duration: "1s"},
+	{Pattern: "solid", Name: "GREEN", Color: "#00FF00", Duration: "1s"},
+	{Pattern: "solid", Name: "BLUE", Color: "#0000FF", Duration: "1s"},
+	{Pattern: "solid", Name: "WHITE", Color: "#FFFFFF"},
+	{Pattern: "solid", Name: "BLACK", Color: "#000000"},
+	{Pattern: "solid", Name: "GRAY 50%", Color: "#808080"},
+	{Pattern: "checkerboard"},
+	{Pattern: "gradients"},
+	{Pattern: "pixel_grid"},
+	{Pattern: "smpte"},
+}
+
+// Шаблоны, которые можно указать в конфигурации, и их названия по умолчанию
+var videoPatternRenderers = map[string]struct {
+	name   string
+	render func(width, height int) string
+}{
+	"checkerboard": {"CHECKERBOARD", checkerboardPattern},
+	"gradients":    {"GRADIENTS", gradientPattern},
+	"pixel_grid":   {"PIXEL GRID", pixelGridPattern},
+	"smpte":        {"SMPTE BARS", drawSMPTETestPattern},
+}
+
+var hexColorRegex = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// Сборка последовательности шаблонов из описания в конфигурации
+func buildVideoSequence(specs []VideoPatternConfig) ([]videoPattern, error) {
+	if len(specs) == 0 {
+		specs = defaultVideoSequence
+	}
+
+	sequence := make([]videoPattern, 0, len(specs))
+	for i, spec := range specs {
+		var pattern videoPattern
+
+		if spec.Pattern == "solid" {
+			if !hexColorRegex.MatchString(spec.Color) {
+				return nil, fmt.Errorf("шаблон %d: для solid нужен цвет в формате #RRGGBB, получено %q", i+1, spec.Color)
+			}
+			pattern = videoPattern{name: strings.ToUpper(spec.Color), render: solidPattern(spec.Color)}
+		} else if renderer, ok := videoPatternRenderers[spec.Pattern]; ok {
+			pattern = videoPattern{name: renderer.name, render: renderer.render}
+		} else {
+			return nil, fmt.Errorf("шаблон %d: неизвестный тип %q", i+1, spec.Pattern)
+		}
+
+		if spec.Name != "" {
+			pattern.name = spec.Name
+		}
+		pattern.render = cachedRender(pattern.render)
+
+		// Пустая длительность или "wait" - ждать нажатия клавиши
+		if spec.Duration != "" && spec.Duration != "wait" {
+			duration, err := time.ParseDuration(spec.Duration)
+			if err != nil || duration <= 0 {
+				return nil, fmt.Errorf("шаблон %d: некорректная длительность %q", i+1, spec.Duration)
+			}
+			pattern.duration = duration
+		}
+
+		sequence = append(sequence, pattern)
+	}
+
+	return sequence, nil
+}
+
+// Кэш последнего кадра шаблона: кадр перерисовывается только при смене размера
+// терминала, а не на каждом тике таймера
+func cachedRender(render func(width, height int) string) func(width, height int) string {
+	var (
+		mu     sync.Mutex
+		size   [2]int
+		frame  string
+		cached bool
+	)
+	return func(width, height int) string {
+		mu.Lock()
+		defer mu.Unlock()
+		if !cached || size != [2]int{width, height} {
+			frame = render(width, height)
+			size = [2]int{width, height}
+			cached = true
+		}
+		return frame
+	}
+}
+
+// Последовательность для модели: переопределение по Product Name или общая
+func videoSequenceFor(cfg VideoTestConfig, productName string) []VideoPatternConfig {
+	for pattern, sequence := range cfg.Models {
+		if strings.Contains(strings.ToLower(productName), strings.ToLower(pattern)) {
+			return sequence
+		}
+	}
+	return cfg.Sequence
+}
+
+// Заливка всего экрана одним цветом
+func solidPattern(color string) func(width, height int) string {
+	return func(width, height int) string {
+		return lipgloss.NewStyle().
+			Background(lipgloss.Color(color)).
+			Width(width).
+			Height(height).
+			Render("")
+	}
+}
+
+// Шахматная доска из квадратов примерно одинакового размера
+func checkerboardPattern(width, height int) string {
+	const cellW, cellH = 8, 4
+	white := lipgloss.NewStyle().Background(lipgloss.Color("#FFFFFF"))
+	black := lipgloss.NewStyle().Background(lipgloss.Color("#000000"))
+
+	rows := make([]string, height)
+	for y := 0; y < height; y++ {
+		var row strings.Builder
+		for x := 0; x < width; x += cellW {
+			w := min(cellW, width-x)
+			style := black
+			if (x/cellW+y/cellH)%2 == 0 {
+				style = white
+			}
+			row.WriteString(style.Render(strings.Repeat(" ", w)))
+		}
+		rows[y] = row.String()
+	}
+	return strings.Join(rows, "\n")
+}
+
+// Плавные градиенты серого и основных цветов для поиска бандинга
+func gradientPattern(width, height int) string {
+	channels := []func(level int) string{
+		func(level int) string { return fmt.Sprintf("#%02X%02X%02X", level, level, level) },
+		func(level int) string { return fmt.Sprintf("#%02X0000", level) },
+		func(level int) string { return fmt.Sprintf("#00%02X00", level) },
+		func(level int) string { return fmt.Sprintf("#0000%02X", level) },
+	}
+
+	// Каждая полоса - одна строка градиента, повторенная по высоте
+	bands := make([]string, len(channels))
+	for i, color := range channels {
+		var row strings.Builder
+		for x := 0; x < width; x++ {
+			level := 0
+			if width > 1 {
+				level = x * 255 / (width - 1)
+			}
+			row.WriteString(lipgloss.NewStyle().Background(lipgloss.Color(color(level))).Render(" "))
+		}
+		bands[i] = row.String()
+	}
+
+	rows := make([]string, height)
+	for y := 0; y < height; y++ {
+		rows[y] = bands[min(y*len(bands)/max(height, 1), len(bands)-1)]
+	}
+	return strings.Join(rows, "\n")
+}
+
+// Самая мелкая сетка, доступная в терминале: квадранты 2x2 в каждой клетке
+func pixelGridPattern(width, height int) string {
+	row := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#000000")).
+		Render(strings.Repeat("▚", width))
+
+	rows := make([]string, height)
+	for y := range rows {
+		rows[y] = row
+	}
+	return strings.Join(rows, "\n")
+}
+
+// Тик таймера видеотеста. Поколение отсекает тики предыдущего запуска.
+type videoTestTimerTickMsg struct {
+	generation int
+}
+
+func videoTestTickCmd(generation int) tea.Cmd {
+	return tea.Tick(videoTestTickInterval, func(time.Time) tea.Msg {
+		return videoTestTimerTickMsg{generation: generation}
+	})
+}
+
+// Поле ввода причины сбоя шаблона
+func newVideoReasonInput() textinput.Model {
+	ti := textinput.New()
+	ti.Placeholder = "Причина (полосы, битые пиксели, оттенок...)"
+	ti.CharLimit = 120
+	ti.Width = 50
+	return ti
+}
+
+// Этап видеотеста
+type videoStage struct {
+	phase         videoPhase
+	sequence      []videoPattern  // Последовательность шаблонов текущего запуска
+	pattern       int             // Номер текущего шаблона
+	patternStart  time.Time       // Время показа текущего шаблона
+	generation    int             // Номер запуска для отсева старых тиков
+	reasonActive  bool            // Вводится причина сбоя шаблона
+	reason        textinput.Model // Поле ввода причины сбоя
+	result        VideoTestResult // Вердикты по шаблонам
+	colorAccepted bool            // Оператор подтвердил запуск без true color
+}
+
+func (videoStage) Name() string { return "video" }
+
+// Запуск видеотеста с первого шаблона
+func (v videoStage) Init(m model) (Stage, tea.Cmd) {
+	// Без true color шаблоны искажаются, сначала предупреждаем оператора
+	if m.config.VideoTest.ColorPolicy != "off" && !m.terminalColors.TrueColor && !v.colorAccepted {
+		v.phase = videoPhaseColorCheck
+		// Профиль попадает в отчет, даже если тест не будет запущен
+		v.result = VideoTestResult{Terminal: m.terminalColors}
+		return v, nil
+	}
+
+	v.phase = videoPhasePatterns
+	v.pattern = 0
+	v.patternStart = time.Now()
+	v.reasonActive = false
+	v.reason = newVideoReasonInput()
+	v.generation++
+
+	// Конфигурация проверена при загрузке, поэтому ошибки здесь быть не может
+	v.sequence, _ = buildVideoSequence(videoSequenceFor(m.config.VideoTest, m.sysInfo.ProductName))
+
+	v.result = VideoTestResult{
+		Patterns: make([]VideoPatternResult, len(v.sequence)),
+		Terminal: m.terminalColors,
+	}
+	for i, pattern := range v.sequence {
+		v.result.Patterns[i].Name = pattern.name
+	}
+	v.result.Patterns[0].Shown = true
+
+	return v, videoTestTickCmd(v.generation)
+}
+
+// Переход к шаблону с указанным номером или к итогам после последнего
+func (v videoStage) showPattern(index int) videoStage {
+	if index >= len(v.sequence) {
+		v.phase = videoPhaseSummary
+		return v
+	}
+
+	v.pattern = max(index, 0)
+	v.patternStart = time.Now()
+	v.result.Patterns[v.pattern].Shown = true
+	return v
+}
+
+// Автоматическая смена шаблонов с заданной длительностью
+func (v videoStage) updateTick(msg videoTestTimerTickMsg) (Stage, tea.Cmd) {
+	if v.phase != videoPhasePatterns || msg.generation != v.generation {
+		return v, nil
+	}
+
+	// Пока оператор вводит причину, шаблон не меняется
+	pattern := v.sequence[v.pattern]
+	if !v.reasonActive && pattern.duration > 0 && time.Since(v.patternStart) >= pattern.duration {
+		v = v.showPattern(v.pattern + 1)
+	}
+
+	if v.phase != videoPhasePatterns {
+		return v, nil
+	}
+	return v, videoTestTickCmd(v.generation)
+}
+
+// Обработка клавиш во время показа шаблонов
+func (v videoStage) updatePatternKey(m model, msg tea.KeyMsg) (Stage, tea.Cmd) {
+	var cmd tea.Cmd
+
+	// Ввод причины сбоя текущего шаблона
+	if v.reasonActive {
+		switch msg.String() {
+		case "enter":
+			reason := strings.TrimSpace(v.reason.Value())
+			if reason == "" {
+				reason = "no reason given"
+			}
+			v.result.Patterns[v.pattern].Failed = true
+			v.result.Patterns[v.pattern].Reason = reason
+			v.reasonActive = false
+			v.reason.Blur()
+			return v.showPattern(v.pattern + 1), nil
+		case "esc":
+			v.reasonActive = false
+			v.reason.Blur()
+			v.patternStart = time.Now()
+			return v, nil
+		}
+		v.reason, cmd = v.reason.Update(msg)
+		return v, cmd
+	}
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return v, tea.Quit
+
+	case "enter", " ", "right":
+		return v.showPattern(v.pattern + 1), nil
+
+	case "left":
+		return v.showPattern(v.pattern - 1), nil
+
+	case "f":
+		v.reasonActive = true
+		v.reason.SetValue(v.result.Patterns[v.pattern].Reason)
+		return v, v.reason.Focus()
+
+	case "b":
+		// Прерывание теста и возврат к экрану системной информации
+		return v, m.request(requestRestart)
+	}
+
+	return v, nil
+}
+
+// Отрисовка текущего шаблона на весь экран со строкой состояния внизу
+func (v videoStage) patternView(m model) string {
+	pattern := v.sequence[v.pattern]
+	areaHeight := max(m.height-1, 0)
+
+	status := fmt.Sprintf("%s (%d/%d)", pattern.name, v.pattern+1, len(v.sequence))
+	if pattern.duration > 0 {
+		remaining := pattern.duration - time.Since(v.patternStart)
+		if remaining < 0 {
+			remaining = 0
+		}
+		status += fmt.Sprintf(" [%.0f sec]", remaining.Seconds())
+	}
+	status += "  [ENTER] Next [←] Back [F] Fail [B] Abort"
+
+	if v.reasonActive {
+		status = fmt.Sprintf("%s FAILED, reason: %s [ENTER] Save [ESC] Cancel", pattern.name, v.reason.View())
+	}
+
+	// Строка состояния не должна переноситься, иначе шаблон сдвинется
+	return pattern.render(m.width, areaHeight) + "\n" + lipgloss.NewStyle().
+		Align(lipgloss.Center).
+		Width(m.width).
+		MaxHeight(1).
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#000000")).
+		Bold(true).
+		Render(status)
+}
+
+// Итоги видеотеста в оверлее
+func (v videoStage) summaryView() string {
+	var lines []string
+	for _, pattern := range v.result.Patterns {
+		switch {
+		case pattern.Failed:
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).
+				Render(fmt.Sprintf("✗ %s: %s", pattern.Name, pattern.Reason)))
+		case pattern.Shown:
+			lines = append(lines, "✓ "+pattern.Name)
+		default:
+			lines = append(lines, "- "+pattern.Name+" (not shown)")
+		}
+	}
+
+	question := "All patterns displayed correctly."
+	if !v.result.Passed() {
+		question = "Some patterns were marked as failed."
+	}
+
+	return fmt.Sprintf(
+		"%s\n\n%s\n\n%s\n\n%s\n\n%s",
+		lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#00AAFF")).Render("Video Test Completed"),
+		strings.Join(lines, "\n"),
+		question+"\nTerminal: "+v.result.Terminal.String(),
+		"[ENTER] Accept results and continue   [n] Run test again",
+		"[B] Return to system information",
+	)
+}
+
+func (v videoStage) Update(m model, msg tea.Msg) (Stage, tea.Cmd) {
+	switch msg := msg.(type) {
+	case videoTestTimerTickMsg:
+		return v.updateTick(msg)
+
+	case stageRetryMsg:
+		return v.Init(m)
+
+	case tea.KeyMsg:
+		// Во время показа шаблонов клавиши управляют видеотестом
+		if v.phase == videoPhasePatterns {
+			return v.updatePatternKey(m, msg)
+		}
+
+		switch msg.String() {
+		case "ctrl+c", "q":
+			return v, tea.Quit
+
+		case "enter":
+			if v.phase == videoPhaseColorCheck {
+				// При отказе тест не запускается, этап записывается как проваленный
+				if m.config.VideoTest.ColorPolicy == "refuse" {
+					return v, m.request(requestNext)
+				}
+				v.colorAccepted = true
+				return v.Init(m)
+			}
+			// Оператор принял вердикты по шаблонам
+			return v, m.request(requestNext)
+
+		case "n":
+			if v.phase == videoPhaseSummary {
+				// Повторяем тест
+				return v, m.request(requestRetry)
+			}
+
+		case "b":
+			// Предупреждение о цветах показывается снова без перезапуска этапа,
+			// возврат к нему не считается повтором
+			return v, m.request(requestBack)
+		}
+	}
+	return v, nil
+}
+
+func (v videoStage) View(m model) string {
+	switch v.phase {
+	case videoPhaseColorCheck:
+		return m.overlayView(m.colorCheckView())
+	case videoPhaseSummary:
+		return m.overlayView(v.summaryView())
+	}
+	return v.patternView(m)
+}
+
+func (v videoStage) Result(m model) StageResult {
+	var res StageResult
+	if v.phase == videoPhaseColorCheck && m.config.VideoTest.ColorPolicy == "refuse" {
+		res.value("terminal", "%s", v.result.Terminal)
+		res.fail("video: refused, terminal has no true color")
+		return res
+	}
+	if v.phase != videoPhaseSummary || len(v.result.Patterns) == 0 {
+		return res
+	}
+
+	shown := 0
+	for _, pattern := range v.result.Patterns {
+		if pattern.Shown {
+			shown++
+		}
+	}
+	res.value("terminal", "%s", v.result.Terminal)
+	res.value("patterns shown", "%d of %d", shown, len(v.result.Patterns))
+
+	if patterns := v.result.failedPatterns(); len(patterns) > 0 {
+		res.fail("video: %s", strings.Join(patterns, ", "))
+	}
+	if shown < len(v.result.Patterns) {
+		res.fail("video: %d patterns not shown", len(v.result.Patterns)-shown)
+	}
+	res.settle()
+	return res
+}
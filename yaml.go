@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Разбор подмножества YAML для файлов комплектации без сторонних зависимостей:
+// блочные словари и списки, списки в квадратных скобках, строки в кавычках,
+// числа, true/false, null и комментарии. Якоря, многострочные строки и
+// словари в фигурных скобках не поддерживаются.
+
+// Непустая строка файла без комментария
+type yamlLine struct {
+	num    int // Номер строки в файле для сообщений об ошибках
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// Разбор документа в значения, которые понимает encoding/json:
+// map[string]any, []any, string, int64, float64, bool и nil
+func parseYAML(data []byte) (any, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("строка %d: отступ табуляцией", i+1)
+		}
+		text = strings.TrimSpace(stripYAMLComment(text))
+		if text == "" || text == "---" {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: text})
+	}
+
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+	value, err := p.parseNode(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("строка %d: неожиданный отступ", p.lines[p.pos].num)
+	}
+	return value, nil
+}
+
+// Комментарий начинается с # в начале строки или после пробела вне кавычек
+func stripYAMLComment(text string) string {
+	var quote rune
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || text[i-1] == ' '):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// Ключ и значение строки словаря. Ключ без кавычек заканчивается на ": "
+// или на двоеточии в конце строки, поэтому order:WO-1 остается ключом целиком.
+func splitYAMLEntry(text string) (string, string, bool) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := strings.IndexRune(text[1:], rune(text[0]))
+		if end < 0 {
+			return "", "", false
+		}
+		key, rest := text[:end+2], strings.TrimSpace(text[end+2:])
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		unquoted, ok := parseYAMLScalar(key).(string)
+		return unquoted, strings.TrimSpace(rest[1:]), ok
+	}
+	if key, value, ok := strings.Cut(text, ": "); ok {
+		return strings.TrimSpace(key), strings.TrimSpace(value), true
+	}
+	if strings.HasSuffix(text, ":") {
+		return strings.TrimSpace(strings.TrimSuffix(text, ":")), "", true
+	}
+	return "", "", false
+}
+
+// Словарь или список, который начинается с текущей строки
+func (p *yamlParser) parseNode(indent int) (any, error) {
+	if p.pos >= len(p.lines) || p.lines[p.pos].indent < indent {
+		return nil, nil
+	}
+	if isYAMLSeqItem(p.lines[p.pos].text) {
+		return p.parseSeq(p.lines[p.pos].indent)
+	}
+	return p.parseMap(p.lines[p.pos].indent)
+}
+
+func (p *yamlParser) parseMap(indent int) (any, error) {
+	values := make(map[string]any)
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if isYAMLSeqItem(line.text) {
+			return nil, fmt.Errorf("строка %d: элемент списка среди ключей словаря", line.num)
+		}
+		key, value, ok := splitYAMLEntry(line.text)
+		if !ok {
+			return nil, fmt.Errorf("строка %d: ожидается ключ: значение", line.num)
+		}
+		if _, dup := values[key]; dup {
+			return nil, fmt.Errorf("строка %d: повторный ключ %q", line.num, key)
+		}
+		p.pos++
+
+		if value != "" {
+			values[key] = parseYAMLScalar(value)
+			continue
+		}
+
+		// Вложенный блок: с большим отступом или список на том же отступе
+		var child any
+		var err error
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			switch {
+			case next.indent > indent:
+				child, err = p.parseNode(next.indent)
+			case next.indent == indent && isYAMLSeqItem(next.text):
+				child, err = p.parseSeq(indent)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[key] = child
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, fmt.Errorf("строка %d: неожиданный отступ", p.lines[p.pos].num)
+	}
+	return values, nil
+}
+
+func (p *yamlParser) parseSeq(indent int) (any, error) {
+	items := []any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSeqItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+
+		var item any
+		var err error
+		switch {
+		case rest == "":
+			p.pos++
+			item, err = p.parseNode(indent + 1)
+		case !strings.HasPrefix(rest, "[") && isYAMLMapEntry(rest):
+			// "- key: value": словарь, остальные ключи которого выровнены по первому
+			col := indent + len(line.text) - len(strings.TrimLeft(line.text[1:], " "))
+			p.lines[p.pos] = yamlLine{num: line.num, indent: col, text: rest}
+			item, err = p.parseMap(col)
+		default:
+			p.pos++
+			item = parseYAMLScalar(rest)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, fmt.Errorf("строка %d: неожиданный отступ", p.lines[p.pos].num)
+	}
+	return items, nil
+}
+
+func isYAMLMapEntry(text string) bool {
+	_, _, ok := splitYAMLEntry(text)
+	return ok
+}
+
+var yamlNumberRe = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+
+// Скалярное значение: строка, число, true/false, null или список [a, b]
+func parseYAMLScalar(text string) any {
+	switch {
+	case strings.HasPrefix(text, "\"") && strings.HasSuffix(text, "\"") && len(text) >= 2:
+		if s, err := strconv.Unquote(text); err == nil {
+			return s
+		}
+		return text[1 : len(text)-1]
+	case strings.HasPrefix(text, "'") && strings.HasSuffix(text, "'") && len(text) >= 2:
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'")
+	case strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]"):
+		items := []any{}
+		if inner := strings.TrimSpace(text[1 : len(text)-1]); inner != "" {
+			for _, item := range strings.Split(inner, ",") {
+				items = append(items, parseYAMLScalar(strings.TrimSpace(item)))
+			}
+		}
+		return items
+	}
+
+	switch strings.ToLower(text) {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null", "~":
+		return nil
+	}
+	if yamlNumberRe.MatchString(text) {
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n
+		}
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+	return text
+}